@@ -131,3 +131,65 @@ type CipherKeyDelegate interface {
 	//   - key      - Symmetric key to cache (must not be nil)
 	CacheCipherKey(sender, receiver ID, key SymmetricKey)
 }
+
+// DigestCipherKeyDelegate defines the interface for resolving cipher keys by digest
+//
+// A group message reusing the key carries the key digest only,
+// the receiver may still keep an earlier key of the sender when the key renewed,
+// so the key store should keep recent keys to be found by digest
+type DigestCipherKeyDelegate interface {
+
+	// GetCipherKeyByDigest retrieves the symmetric key matching the digest
+	//
+	// Parameters:
+	//   - sender   - From where (user or contact ID)
+	//   - receiver - To where (contact or user/group ID)
+	//   - digest   - Digest of the key (see GetKeyDigest)
+	// Returns: SymmetricKey matched (nil if not found)
+	GetCipherKeyByDigest(sender, receiver ID, digest string) SymmetricKey
+}
+
+// GroupKeyDelegate defines the interface for tracking group key distribution
+//
+// Records which members already hold the group key (by key digest),
+// so the sender only needs to encrypt the key for the members who don't have it yet;
+// when the group membership changes, the key will be renewed and distributed to all members again
+type GroupKeyDelegate interface {
+	CipherKeyDelegate
+
+	// GetKeyMembers retrieves the group members when the key was distributed
+	//
+	// Parameters:
+	//   - sender - Group message sender (local user ID)
+	//   - group  - Group ID
+	//   - digest - Digest of the group key
+	// Returns: Member IDs (nil if the key has not been distributed yet)
+	GetKeyMembers(sender, group ID, digest string) []ID
+
+	// SaveKeyMembers stores the group members for the key distribution
+	//
+	// Parameters:
+	//   - members - Current group members
+	//   - sender  - Group message sender (local user ID)
+	//   - group   - Group ID
+	//   - digest  - Digest of the group key
+	SaveKeyMembers(members []ID, sender, group ID, digest string)
+
+	// GetKeyHolders retrieves the members who already hold the group key
+	//
+	// Parameters:
+	//   - sender - Group message sender (local user ID)
+	//   - group  - Group ID
+	//   - digest - Digest of the group key
+	// Returns: Member IDs (empty slice if nobody got the key yet)
+	GetKeyHolders(sender, group ID, digest string) []ID
+
+	// SaveKeyHolders stores the members who hold the group key
+	//
+	// Parameters:
+	//   - holders - Members who have received the key
+	//   - sender  - Group message sender (local user ID)
+	//   - group   - Group ID
+	//   - digest  - Digest of the group key
+	SaveKeyHolders(holders []ID, sender, group ID, digest string)
+}
@@ -0,0 +1,319 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"encoding/binary"
+	"math"
+
+	. "github.com/dimchat/mkm-go/format"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+/** Binary Message Format
+<pre>
+    +--------+---------+-----------------------------------+
+    | magic  | version | fields: [tag, length, value] * N  |
+    | 0xD1 M | 0x01    |   tag    - 1 byte                 |
+    |        |         |   length - uvarint                |
+    |        |         |   value  - length bytes           |
+    +--------+---------+-----------------------------------+
+
+    Base64 fields ("data", "signature", "key") are stored as raw bytes,
+    each entry in "keys" is stored as [name length, name, flag, value],
+    other fields not listed below are stored as JSON in tag 0x7F.
+</pre>
+*/
+
+var binaryMagic = []byte{0xD1, 'M', 0x01}
+
+const (
+	binaryTagSender    = 0x01
+	binaryTagReceiver  = 0x02
+	binaryTagTime      = 0x03 // float64, big endian
+	binaryTagType      = 0x04
+	binaryTagGroup     = 0x05
+	binaryTagData      = 0x06 // raw bytes
+	binaryTagDataText  = 0x07 // string (broadcast message)
+	binaryTagSignature = 0x08 // raw bytes
+	binaryTagKey       = 0x09 // raw bytes
+	binaryTagKeys      = 0x0A // entries
+	binaryTagExtra     = 0x7F // JSON
+)
+
+func IsBinaryMessage(data []byte) bool {
+	if len(data) < len(binaryMagic) {
+		return false
+	}
+	for i, b := range binaryMagic {
+		if data[i] != b {
+			return false
+		}
+	}
+	return true
+}
+
+// BinaryCompressor serializes ReliableMessage to compact binary format,
+// content and symmetric key are still serialized as JSON
+type BinaryCompressor struct {
+	*MessageCompressor
+}
+
+func NewBinaryCompressor(shortener Shortener) *BinaryCompressor {
+	return &BinaryCompressor{
+		MessageCompressor: NewMessageCompressor(shortener),
+	}
+}
+
+// Override
+func (compressor *BinaryCompressor) CompressReliableMessage(msg StringKeyMap) []byte {
+	return EncodeBinaryMessage(msg)
+}
+
+// EncodeBinaryMessage serializes message info to binary format
+func EncodeBinaryMessage(msg StringKeyMap) []byte {
+	out := make([]byte, 0, 512)
+	out = append(out, binaryMagic...)
+	extra := NewMap()
+	for name, value := range msg {
+		switch name {
+		case "sender":
+			out = appendBinaryText(out, binaryTagSender, value, extra, name)
+		case "receiver":
+			out = appendBinaryText(out, binaryTagReceiver, value, extra, name)
+		case "type":
+			out = appendBinaryText(out, binaryTagType, value, extra, name)
+		case "group":
+			out = appendBinaryText(out, binaryTagGroup, value, extra, name)
+		case "time":
+			number := ConvertFloat64(value, math.NaN())
+			if math.IsNaN(number) {
+				extra[name] = value
+				continue
+			}
+			buf := make([]byte, 8)
+			binary.BigEndian.PutUint64(buf, math.Float64bits(number))
+			out = appendBinaryField(out, binaryTagTime, buf)
+		case "data":
+			if raw := binaryDecodeBase64(value); raw != nil {
+				out = appendBinaryField(out, binaryTagData, raw)
+			} else {
+				out = appendBinaryText(out, binaryTagDataText, value, extra, name)
+			}
+		case "signature":
+			if raw := binaryDecodeBase64(value); raw != nil {
+				out = appendBinaryField(out, binaryTagSignature, raw)
+			} else {
+				extra[name] = value
+			}
+		case "key":
+			if raw := binaryDecodeBase64(value); raw != nil {
+				out = appendBinaryField(out, binaryTagKey, raw)
+			} else {
+				extra[name] = value
+			}
+		case "keys":
+			if keys, ok := value.(StringKeyMap); ok {
+				out = appendBinaryField(out, binaryTagKeys, encodeBinaryKeys(keys))
+			} else {
+				extra[name] = value
+			}
+		default:
+			extra[name] = value
+		}
+	}
+	if len(extra) > 0 {
+		json := JSONEncodeMap(extra)
+		out = appendBinaryField(out, binaryTagExtra, UTF8Encode(json))
+	}
+	return out
+}
+
+// DecodeBinaryMessage parses message info from binary format
+func DecodeBinaryMessage(data []byte) StringKeyMap {
+	if !IsBinaryMessage(data) {
+		return nil
+	}
+	info := NewMap()
+	var tag byte
+	var value []byte
+	rest := data[len(binaryMagic):]
+	for len(rest) > 0 {
+		tag, value, rest = readBinaryField(rest)
+		if value == nil {
+			//panic("binary message error")
+			return nil
+		}
+		switch tag {
+		case binaryTagSender:
+			info["sender"] = UTF8Decode(value)
+		case binaryTagReceiver:
+			info["receiver"] = UTF8Decode(value)
+		case binaryTagType:
+			info["type"] = UTF8Decode(value)
+		case binaryTagGroup:
+			info["group"] = UTF8Decode(value)
+		case binaryTagTime:
+			if len(value) != 8 {
+				return nil
+			}
+			info["time"] = math.Float64frombits(binary.BigEndian.Uint64(value))
+		case binaryTagData:
+			info["data"] = Base64Encode(value)
+		case binaryTagDataText:
+			info["data"] = UTF8Decode(value)
+		case binaryTagSignature:
+			info["signature"] = Base64Encode(value)
+		case binaryTagKey:
+			info["key"] = Base64Encode(value)
+		case binaryTagKeys:
+			keys := decodeBinaryKeys(value)
+			if keys == nil {
+				return nil
+			}
+			info["keys"] = keys
+		case binaryTagExtra:
+			extra := JSONDecodeMap(UTF8Decode(value))
+			for k, v := range extra {
+				info[k] = v
+			}
+		default:
+			// unknown tag, ignore it
+		}
+	}
+	return info
+}
+
+func encodeBinaryKeys(keys StringKeyMap) []byte {
+	out := make([]byte, 0, 256*len(keys))
+	var entry []byte
+	for name, value := range keys {
+		entry = UTF8Encode(name)
+		out = appendUvarint(out, uint64(len(entry)))
+		out = append(out, entry...)
+		if raw := binaryDecodeBase64(value); raw != nil {
+			out = appendBinaryField(out, 0, raw)
+		} else {
+			text, _ := value.(string)
+			out = appendBinaryField(out, 1, UTF8Encode(text))
+		}
+	}
+	return out
+}
+
+func decodeBinaryKeys(data []byte) StringKeyMap {
+	keys := NewMap()
+	var flag byte
+	var value []byte
+	for len(data) > 0 {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			return nil
+		}
+		name := UTF8Decode(data[n : n+int(size)])
+		flag, value, data = readBinaryField(data[n+int(size):])
+		if value == nil {
+			return nil
+		} else if flag == 0 {
+			keys[name] = Base64Encode(value)
+		} else {
+			keys[name] = UTF8Decode(value)
+		}
+	}
+	return keys
+}
+
+func appendBinaryField(out []byte, tag byte, value []byte) []byte {
+	out = append(out, tag)
+	out = appendUvarint(out, uint64(len(value)))
+	return append(out, value...)
+}
+
+func appendUvarint(out []byte, x uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, x)
+	return append(out, buf[:n]...)
+}
+
+func appendBinaryText(out []byte, tag byte, value interface{}, extra StringKeyMap, name string) []byte {
+	text, ok := value.(string)
+	if !ok {
+		extra[name] = value
+		return out
+	}
+	return appendBinaryField(out, tag, UTF8Encode(text))
+}
+
+// returns: tag, value (nil on error), rest
+func readBinaryField(data []byte) (byte, []byte, []byte) {
+	if len(data) < 2 {
+		return 0, nil, nil
+	}
+	tag := data[0]
+	size, n := binary.Uvarint(data[1:])
+	if n <= 0 || uint64(len(data)-1-n) < size {
+		return tag, nil, nil
+	}
+	start := 1 + n
+	end := start + int(size)
+	return tag, data[start:end:end], data[end:]
+}
+
+// decode base64 string only if it can be encoded back exactly
+func binaryDecodeBase64(value interface{}) []byte {
+	text, ok := value.(string)
+	if !ok || text == "" {
+		return nil
+	}
+	raw := Base64Decode(text)
+	if len(raw) == 0 || Base64Encode(raw) != text {
+		return nil
+	}
+	return raw
+}
+
+//
+//  Factory
+//
+
+// BinaryCompressFactory creates compressor for binary message format,
+// usage:
+//
+//	SetCompressFactory(&BinaryCompressFactory{})
+type BinaryCompressFactory struct {
+	//CompressFactory
+}
+
+// Override
+func (BinaryCompressFactory) CreateCompressor() Compressor {
+	shortener := NewShortener()
+	return NewBinaryCompressor(shortener)
+}
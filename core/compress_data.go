@@ -0,0 +1,224 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/sdk-go/mkm"
+)
+
+const (
+	// CompressThreshold is the minimum size of data to be compressed
+	CompressThreshold = 1024
+
+	// MaxExtractSize limits the size of decompressed data
+	MaxExtractSize = 16 * 1024 * 1024
+)
+
+// DataCompressor compresses serialized data (content before encryption, or network package)
+//
+// Compressed data starts with gzip header (0x1F 0x8B), so it can be distinguished
+// from plain JSON automatically.
+type DataCompressor interface {
+
+	// CompressData compresses data if it is big enough and compressing makes it smaller
+	//
+	// Returns: compressed data, or the original data
+	CompressData(data []byte) []byte
+
+	// ExtractData decompresses data if it is marked as compressed
+	//
+	// Returns: decompressed data, the original data if not compressed, or nil on error
+	ExtractData(data []byte) []byte
+}
+
+func IsCompressedData(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1F && data[1] == 0x8B
+}
+
+func gzipCompress(data []byte) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil
+	}
+	if err := writer.Close(); err != nil {
+		return nil
+	}
+	return buffer.Bytes()
+}
+
+func gzipExtract(data []byte) []byte {
+	return gzipExtractWithLimit(data, MaxExtractSize)
+}
+
+func gzipExtractWithLimit(data []byte, limit int) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+	// limit the output to prevent decompression bomb
+	out, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil || len(out) > limit {
+		return nil
+	}
+	return out
+}
+
+// CompressionDelegate decides whether the peer can accept compressed data,
+// so that old peers will still receive plain JSON
+type CompressionDelegate interface {
+
+	// IsCompressionSupported checks the peer's capability
+	//
+	// Parameters:
+	//   - peer - Receiver ID (user or group) for content, or the next hop for package
+	// Returns: true if the peer can extract compressed data
+	IsCompressionSupported(peer ID) bool
+
+	// SetCompressionSupported updates the peer's capability
+	//
+	// Parameters:
+	//   - peer      - User ID
+	//   - supported - Capability (from handshake/visa, or a compressed message received)
+	SetCompressionSupported(peer ID, supported bool)
+}
+
+// CompressionVisaKey is the visa property for announcing compression capability
+//
+//	visa.data: {
+//	    ...
+//	    "compression" : ["gzip"]
+//	}
+const CompressionVisaKey = "compression"
+
+// SetVisaCompression announces the compression capability in the visa,
+// NOTICE: the visa must be signed again after this
+func SetVisaCompression(visa Document) {
+	visa.SetProperty(CompressionVisaKey, []interface{}{"gzip"})
+}
+
+// IsVisaCompressionSupported checks the compression capability announced in documents
+//
+// Returns: (supported, announced)
+func IsVisaCompressionSupported(documents []Document) (bool, bool) {
+	for _, doc := range documents {
+		value := doc.GetProperty(CompressionVisaKey)
+		if value == nil {
+			continue
+		}
+		array, _ := value.([]interface{})
+		for _, item := range array {
+			if item == "gzip" {
+				return true, true
+			}
+		}
+		return false, true
+	}
+	return false, false
+}
+
+// CompressionNegotiator is a memory cache for peers' compression capability
+//
+//	The capability of a user is decided by:
+//	    1. explicit setting, or learned from the compressed data received from it;
+//	    2. the 'compression' property in its visa (if DataSource set);
+//	    3. DefaultSupported (explicit opt-in for all peers).
+//	A group is supported only when all its members are supported.
+type CompressionNegotiator struct {
+	//CompressionDelegate
+
+	mutex sync.RWMutex
+	peers map[string]bool
+
+	// DataSource provides visa documents & group members (optional)
+	DataSource EntityDataSource
+
+	// DefaultSupported is used for peers whose capability is unknown
+	DefaultSupported bool
+}
+
+func NewCompressionNegotiator() *CompressionNegotiator {
+	return &CompressionNegotiator{
+		peers: make(map[string]bool),
+	}
+}
+
+// Override
+func (negotiator *CompressionNegotiator) IsCompressionSupported(peer ID) bool {
+	if peer.IsBroadcast() {
+		return false
+	} else if !peer.IsGroup() {
+		return negotiator.isUserSupported(peer)
+	}
+	dataSource := negotiator.DataSource
+	if dataSource == nil {
+		return negotiator.isUserSupported(peer)
+	}
+	members := dataSource.GetMembers(peer)
+	if len(members) == 0 {
+		return false
+	}
+	for _, item := range members {
+		if !negotiator.isUserSupported(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func (negotiator *CompressionNegotiator) isUserSupported(peer ID) bool {
+	negotiator.mutex.RLock()
+	supported, learned := negotiator.peers[peer.String()]
+	negotiator.mutex.RUnlock()
+	if learned {
+		return supported
+	}
+	if dataSource := negotiator.DataSource; dataSource != nil {
+		if supported, announced := IsVisaCompressionSupported(dataSource.GetDocuments(peer)); announced {
+			return supported
+		}
+	}
+	return negotiator.DefaultSupported
+}
+
+// Override
+func (negotiator *CompressionNegotiator) SetCompressionSupported(peer ID, supported bool) {
+	negotiator.mutex.Lock()
+	defer negotiator.mutex.Unlock()
+	negotiator.peers[peer.String()] = supported
+}
@@ -99,13 +99,23 @@ func (shortener *MessageShortener) RestoreKeys(keys []string, info StringKeyMap)
 
 // Override
 func (shortener *MessageShortener) CompressContent(content StringKeyMap) StringKeyMap {
+	// check extended keys for content type before shortening 'type'
+	keys := GetContentShortKeys(shortKeysContentType(content))
 	shortener.ShortenKeys(shortener.contentShortKeys, content)
+	if len(keys) > 0 {
+		shortener.ShortenKeys(keys, content)
+	}
 	return content
 }
 
 // Override
 func (shortener *MessageShortener) ExtractContent(content StringKeyMap) StringKeyMap {
 	shortener.RestoreKeys(shortener.contentShortKeys, content)
+	// check extended keys for content type after 'type' restored
+	keys := GetContentShortKeys(shortKeysContentType(content))
+	if len(keys) > 0 {
+		shortener.RestoreKeys(keys, content)
+	}
 	return content
 }
 
@@ -0,0 +1,182 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"strconv"
+	"sync"
+
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+/**
+ *  Short keys for content types
+ *
+ *      Additional short keys can be registered for a content type,
+ *      they are applied after the common content keys (ShortKeys.ContentKeys).
+ *
+ *      NOTICE: all peers must register the same tables,
+ *              otherwise the extended keys cannot be restored.
+ *
+ *  Usage:
+ *
+ *      RegisterContentShortKeys(ContentType.FILE, []string{
+ *          "U", "URL",
+ *          "f", "filename",
+ *      })
+ */
+
+type ContentShortKeys struct {
+	mutex  sync.RWMutex
+	tables map[MessageType][]string
+}
+
+func NewContentShortKeys() *ContentShortKeys {
+	return &ContentShortKeys{
+		tables: make(map[MessageType][]string),
+	}
+}
+
+// GetKeys returns the extended short keys for content type
+func (registry *ContentShortKeys) GetKeys(msgType MessageType) []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	return registry.tables[msgType]
+}
+
+// FindConflict checks the short keys for content type
+//
+// Parameters:
+//   - msgType - content type
+//   - keys    - short/long key pairs: [short1, long1, short2, long2, ...]
+//
+// Returns: the first conflicted key (or the other key of an empty one), empty string if no conflict
+func (registry *ContentShortKeys) FindConflict(msgType MessageType, keys []string) string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	return registry.findConflict(msgType, keys)
+}
+
+func (registry *ContentShortKeys) findConflict(msgType MessageType, keys []string) string {
+	if len(keys)%2 != 0 {
+		// not in pairs
+		return keys[len(keys)-1]
+	}
+	// 1. check empty keys & common keys
+	common := ShortKeys.ContentKeys
+	for i, name := range keys {
+		if name == "" {
+			// return the other key in this pair
+			if i%2 == 0 {
+				return keys[i+1] + "(short key empty)"
+			}
+			return keys[i-1] + "(long key empty)"
+		} else if shortKeysContain(common, name) {
+			return name
+		}
+	}
+	// 2. check with registered keys & each other
+	exists := registry.tables[msgType]
+	table := make([]string, 0, len(exists)+len(keys))
+	table = append(table, exists...)
+	var short, long string
+	for i := 1; i < len(keys); i += 2 {
+		short, long = keys[i-1], keys[i]
+		for j := 1; j < len(table); j += 2 {
+			if table[j-1] == short && table[j] == long {
+				// same pair, ignore it
+				break
+			} else if table[j-1] == short || table[j] == short {
+				return short
+			} else if table[j-1] == long || table[j] == long {
+				return long
+			}
+		}
+		table = append(table, short, long)
+	}
+	return ""
+}
+
+// Register adds short keys for content type
+//
+// Parameters:
+//   - msgType - content type
+//   - keys    - short/long key pairs: [short1, long1, short2, long2, ...]
+//
+// Returns: false on conflict
+func (registry *ContentShortKeys) Register(msgType MessageType, keys []string) bool {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if registry.findConflict(msgType, keys) != "" {
+		//panic("short keys conflict")
+		return false
+	}
+	table := registry.tables[msgType]
+	for i := 1; i < len(keys); i += 2 {
+		if !shortKeysContain(table, keys[i]) {
+			table = append(table, keys[i-1], keys[i])
+		}
+	}
+	registry.tables[msgType] = table
+	return true
+}
+
+func shortKeysContain(keys []string, name string) bool {
+	for _, item := range keys {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+func shortKeysContentType(content StringKeyMap) MessageType {
+	value := content["type"]
+	if text, ok := value.(string); ok {
+		return text
+	}
+	number := ConvertInt(value, -1)
+	if number < 0 {
+		return ""
+	}
+	return strconv.Itoa(number)
+}
+
+var sharedContentShortKeys = NewContentShortKeys()
+
+func RegisterContentShortKeys(msgType MessageType, keys []string) bool {
+	return sharedContentShortKeys.Register(msgType, keys)
+}
+
+func GetContentShortKeys(msgType MessageType) []string {
+	return sharedContentShortKeys.GetKeys(msgType)
+}
@@ -0,0 +1,151 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/dimchat/mkm-go/types"
+)
+
+func TestContentShortKeysFindConflict(t *testing.T) {
+	registry := NewContentShortKeys()
+	if !registry.Register("16", []string{"U", "URL", "f", "filename"}) {
+		t.Fatal("failed to register short keys")
+	}
+	tests := []struct {
+		name     string
+		msgType  string
+		keys     []string
+		conflict string
+	}{
+		{"no conflict", "16", []string{"s", "size"}, ""},
+		{"same pair registered", "16", []string{"U", "URL"}, ""},
+		{"other content type", "20", []string{"U", "url"}, ""},
+		{"common short key", "16", []string{"T", "title"}, "T"},
+		{"common long key", "16", []string{"y", "type"}, "type"},
+		{"short key registered", "16", []string{"U", "uri"}, "U"},
+		{"long key registered", "16", []string{"u", "URL"}, "URL"},
+		{"short key used as long key", "16", []string{"x", "f"}, "f"},
+		{"duplicated in keys", "16", []string{"a", "alpha", "a", "apple"}, "a"},
+		{"empty short key", "16", []string{"", "empty"}, "empty(short key empty)"},
+		{"empty long key", "16", []string{"e", ""}, "e(long key empty)"},
+		{"not in pairs", "16", []string{"s", "size", "d"}, "d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict := registry.FindConflict(tt.msgType, tt.keys)
+			if conflict != tt.conflict {
+				t.Errorf("FindConflict(%v) = %q, want %q", tt.keys, conflict, tt.conflict)
+			}
+		})
+	}
+}
+
+func TestContentShortKeysRegister(t *testing.T) {
+	registry := NewContentShortKeys()
+	tests := []struct {
+		keys []string
+		ok   bool
+		want []string
+	}{
+		{[]string{"U", "URL"}, true, []string{"U", "URL"}},
+		{[]string{"U", "URL", "f", "filename"}, true, []string{"U", "URL", "f", "filename"}},
+		{[]string{"U", "uri"}, false, []string{"U", "URL", "f", "filename"}},
+		{[]string{"N", "name"}, false, []string{"U", "URL", "f", "filename"}},
+		{[]string{"", "empty"}, false, []string{"U", "URL", "f", "filename"}},
+	}
+	for i, tt := range tests {
+		if ok := registry.Register("16", tt.keys); ok != tt.ok {
+			t.Errorf("#%d Register(%v) = %v, want %v", i, tt.keys, ok, tt.ok)
+		}
+		if got := registry.GetKeys("16"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d GetKeys() = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestContentShortKeysRoundTrip(t *testing.T) {
+	// custom content type, not used by other tests
+	const msgType = "201"
+	if !RegisterContentShortKeys(msgType, []string{"U", "URL", "f", "filename"}) {
+		t.Fatal("failed to register short keys")
+	}
+	shortener := NewMessageShortener(ShortKeys.ContentKeys, ShortKeys.CryptoKeys, ShortKeys.MessageKeys)
+	tests := []StringKeyMap{
+		{
+			"type":     msgType,
+			"sn":       123,
+			"time":     1.5,
+			"URL":      "https://example.com/a.png",
+			"filename": "a.png",
+			"extra":    "kept",
+		},
+		{
+			"type":     201,
+			"sn":       456,
+			"filename": "b.png",
+		},
+		{
+			// other content type, the custom keys must not be applied
+			"type":     "16",
+			"sn":       789,
+			"URL":      "https://example.com/c.png",
+			"filename": "c.png",
+		},
+	}
+	for i, original := range tests {
+		content := copyStringKeyMap(original)
+		compressed := shortener.CompressContent(content)
+		_, hasURL := compressed["URL"]
+		_, hasU := compressed["U"]
+		if original["type"] == "16" {
+			if hasU || !hasURL {
+				t.Errorf("#%d custom keys applied to other type: %v", i, compressed)
+			}
+		} else if _, exists := original["URL"]; exists && (hasURL || !hasU) {
+			t.Errorf("#%d custom keys not applied: %v", i, compressed)
+		}
+		extracted := shortener.ExtractContent(compressed)
+		if !reflect.DeepEqual(extracted, original) {
+			t.Errorf("#%d round trip = %v, want %v", i, extracted, original)
+		}
+	}
+}
+
+func copyStringKeyMap(dict StringKeyMap) StringKeyMap {
+	clone := make(StringKeyMap, len(dict))
+	for key, value := range dict {
+		clone[key] = value
+	}
+	return clone
+}
@@ -59,16 +59,51 @@ type Compressor interface {
 	ExtractReliableMessage(data []byte) StringKeyMap
 }
 
+// WireFormatExtractor extracts the ReliableMessage with the wire format it was in,
+// so the format can be learned without decoding the package again
+type WireFormatExtractor interface {
+
+	// ExtractReliableMessageWithFormat is the same as ExtractReliableMessage,
+	// and returns the format detected before restoring short keys
+	ExtractReliableMessageWithFormat(data []byte) (StringKeyMap, WireFormat)
+}
+
 type MessageCompressor struct {
 	//Compressor
+	//DataCompressor
 
 	shortener Shortener
+
+	// Threshold is the minimum size of data to be compressed
+	Threshold int
 }
 
 func NewMessageCompressor(shortener Shortener) *MessageCompressor {
 	return &MessageCompressor{
 		shortener: shortener,
+		Threshold: CompressThreshold,
+	}
+}
+
+// Override
+func (compressor *MessageCompressor) CompressData(data []byte) []byte {
+	if len(data) < compressor.Threshold || IsCompressedData(data) {
+		return data
+	}
+	out := gzipCompress(data)
+	if len(out) == 0 || len(out) >= len(data) {
+		// not worth it
+		return data
+	}
+	return out
+}
+
+// Override
+func (compressor *MessageCompressor) ExtractData(data []byte) []byte {
+	if IsCompressedData(data) {
+		return gzipExtract(data)
 	}
+	return data
 }
 
 // Override
@@ -79,7 +114,7 @@ func (compressor *MessageCompressor) CompressContent(content StringKeyMap, _ Str
 
 // Override
 func (compressor *MessageCompressor) ExtractContent(data []byte, _ StringKeyMap) StringKeyMap {
-	info := jsonDecode(data)
+	info := jsonDecode(compressor.ExtractData(data))
 	if info != nil {
 		info = compressor.shortener.ExtractContent(info)
 	}
@@ -109,11 +144,23 @@ func (compressor *MessageCompressor) CompressReliableMessage(msg StringKeyMap) [
 
 // Override
 func (compressor *MessageCompressor) ExtractReliableMessage(data []byte) StringKeyMap {
+	info, _ := compressor.ExtractReliableMessageWithFormat(data)
+	return info
+}
+
+// Override
+func (compressor *MessageCompressor) ExtractReliableMessageWithFormat(data []byte) (StringKeyMap, WireFormat) {
+	data = compressor.ExtractData(data)
+	if IsBinaryMessage(data) {
+		// binary format
+		return DecodeBinaryMessage(data), WireFormatBinary
+	}
 	info := jsonDecode(data)
+	format := DetectWireFormatOfMap(info)
 	if info != nil {
 		info = compressor.shortener.ExtractReliableMessage(info)
 	}
-	return info
+	return info, format
 }
 
 func jsonEncode(dict StringKeyMap) []byte {
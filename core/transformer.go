@@ -31,6 +31,8 @@
 package sdk
 
 import (
+	"strings"
+
 	. "github.com/dimchat/core-go/msg"
 	. "github.com/dimchat/dkd-go/protocol"
 	. "github.com/dimchat/mkm-go/crypto"
@@ -87,37 +89,206 @@ type MessageTransformer struct {
 	//
 	// Optimizes network transmission size of serialized message data
 	Compressor Compressor
+
+	// CompressionDelegate decides whether the content for a receiver can be compressed
+	//
+	// Content is compressed before encryption, so it must be supported end to end;
+	// if not set, content will never be compressed (but compressed data can still be extracted)
+	CompressionDelegate CompressionDelegate
+
+	// PackageCompressionDelegate decides whether the package for the next hop can be compressed
+	//
+	// Package is compressed for one hop only (the station, or the directly connected peer);
+	// if not set, packages will never be compressed (but can still be extracted)
+	PackageCompressionDelegate CompressionDelegate
+
+	// Station is the next hop for all packages (client only)
+	//
+	// If not set, the packages are sent to the receivers directly
+	Station ID
+
+	// WireFormatDelegate decides the message format for each peer
+	//
+	// If not set, the format of Compressor will be used for all peers
+	WireFormatDelegate WireFormatDelegate
+
+	// Validator checks incoming packages before parsing
+	//
+	// If not set, packages will be parsed without validation
+	Validator PackageValidator
 }
 
 func NewMessageTransformer(facebook EntityDelegate) *MessageTransformer {
 	return &MessageTransformer{
-		EntityDelegate: facebook,
-		Compressor:     CreateCompressor(),
+		EntityDelegate:             facebook,
+		Compressor:                 CreateCompressor(),
+		CompressionDelegate:        nil,
+		PackageCompressionDelegate: nil,
+		Station:                    nil,
+		WireFormatDelegate:         nil,
+		Validator:                  nil,
 	}
 }
 
+// protected
+func (transformer *MessageTransformer) nextHop(peer ID) ID {
+	if station := transformer.Station; station != nil {
+		// all packages go through the station
+		return station
+	}
+	return peer
+}
+
 func (transformer *MessageTransformer) SerializeMessage(rMsg ReliableMessage) []byte {
-	compressor := transformer.Compressor
-	info := rMsg.Map()
-	return compressor.CompressReliableMessage(info)
+	return transformer.SerializeMessageTo(rMsg, transformer.nextHop(rMsg.Receiver()))
+}
+
+// SerializeMessageTo converts the message to binary data in the format for the peer
+//
+// Parameters:
+//   - rMsg - Message to be sent
+//   - peer - Next hop: the receiver, or the station which will deliver this message
+//
+// Returns: Binary data package
+func (transformer *MessageTransformer) SerializeMessageTo(rMsg ReliableMessage, peer ID) []byte {
+	var data []byte
+	delegate := transformer.WireFormatDelegate
+	var format WireFormat
+	if delegate != nil {
+		format = delegate.GetWireFormat(peer)
+	}
+	switch format {
+	case WireFormatLongKeys:
+		data = jsonEncode(rMsg.Map())
+	case WireFormatShortKeys:
+		shortener := NewShortener()
+		data = jsonEncode(shortener.CompressReliableMessage(rMsg.CopyMap(false)))
+	case WireFormatBinary:
+		data = EncodeBinaryMessage(rMsg.Map())
+	default:
+		compressor := transformer.Compressor
+		info := rMsg.Map()
+		data = compressor.CompressReliableMessage(info)
+	}
+	return transformer.compressData(data, transformer.PackageCompressionDelegate, peer)
 }
 
 func (transformer *MessageTransformer) DeserializeMessage(data []byte) ReliableMessage {
-	compressor := transformer.Compressor
-	info := compressor.ExtractReliableMessage(data)
-	return ParseReliableMessage(info)
+	rMsg, _ := transformer.ParsePackage(data)
+	return rMsg
+}
+
+// ParsePackage validates and parses the network package
+//
+// Parameters:
+//   - data - Binary data package received from network
+//
+// Returns: Structured ReliableMessage, or validation error
+func (transformer *MessageTransformer) ParsePackage(data []byte) (ReliableMessage, error) {
+	validator := transformer.Validator
+	extracted := data
+	if validator != nil {
+		if err := validator.ValidatePackage(data); err != nil {
+			return nil, err
+		}
+		// check the size limit after decompression,
+		// before decoding the whole message
+		var err error
+		if extracted, err = validator.ExtractPackage(data); err != nil {
+			return nil, err
+		} else if err = validator.ValidatePackage(extracted); err != nil {
+			return nil, err
+		}
+	}
+	var info StringKeyMap
+	var format WireFormat
+	if extractor, ok := transformer.Compressor.(WireFormatExtractor); ok {
+		info, format = extractor.ExtractReliableMessageWithFormat(extracted)
+	} else {
+		compressor := transformer.Compressor
+		info = compressor.ExtractReliableMessage(extracted)
+	}
+	if info == nil {
+		return nil, &ValidationError{Field: "package", Reason: "failed to extract"}
+	} else if validator != nil {
+		if err := validator.ValidateMessage(info); err != nil {
+			return nil, err
+		}
+	}
+	rMsg := ParseReliableMessage(info)
+	if rMsg == nil {
+		return nil, &ValidationError{Field: "message", Reason: "failed to parse"}
+	}
+	StripLocalKeys(rMsg)
+	if IsCompressedData(data) {
+		// the package is compressed by the previous hop, not the sender
+		hop := transformer.nextHop(rMsg.Sender())
+		transformer.learnCompression(transformer.PackageCompressionDelegate, hop)
+	}
+	transformer.learnWireFormat(format, rMsg)
+	return rMsg, nil
+}
+
+// StripLocalKeys removes the local-only keys ('_' prefixed) from the message received,
+// they are set by the local processors, and must not be trusted from network data
+func StripLocalKeys(msg Mapper) {
+	var keys []string
+	for key := range msg.Map() {
+		if strings.HasPrefix(key, "_") {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		msg.Remove(key)
+	}
+}
+
+// protected
+func (transformer *MessageTransformer) learnWireFormat(format WireFormat, rMsg ReliableMessage) {
+	// reply in the format which the peer (next hop) sent
+	delegate := transformer.WireFormatDelegate
+	if delegate == nil || format == WireFormatUnknown {
+		return
+	}
+	delegate.SetWireFormat(format, transformer.nextHop(rMsg.Sender()))
+}
+
+// protected
+func (transformer *MessageTransformer) compressData(data []byte, delegate CompressionDelegate, peer ID) []byte {
+	if delegate == nil || !delegate.IsCompressionSupported(peer) {
+		// old peer, send plain JSON
+		return data
+	}
+	if compressor, ok := transformer.Compressor.(DataCompressor); ok {
+		return compressor.CompressData(data)
+	}
+	return data
+}
+
+// protected
+func (transformer *MessageTransformer) learnCompression(delegate CompressionDelegate, peer ID) {
+	// the peer sent compressed data, so it can extract it too
+	if delegate != nil {
+		delegate.SetCompressionSupported(peer, true)
+	}
 }
 
 //-------- InstantMessageDelegate
 
 // Override
-func (transformer *MessageTransformer) SerializeContent(content Content, password SymmetricKey, _ InstantMessage) []byte {
+func (transformer *MessageTransformer) SerializeContent(content Content, password SymmetricKey, iMsg InstantMessage) []byte {
 	// NOTICE: check attachment for File/Image/Audio/Video message content
 	//         before serialize content, this job should be done in subclass
 	compressor := transformer.Compressor
 	info := content.Map()
 	dict := password.Map()
-	return compressor.CompressContent(info, dict)
+	data := compressor.CompressContent(info, dict)
+	if IsBroadcastMessage(iMsg) {
+		// broadcast message content will not be encrypted,
+		// keep it as plain JSON
+		return data
+	}
+	return transformer.compressData(data, transformer.CompressionDelegate, iMsg.Receiver())
 }
 
 // Override
@@ -258,11 +429,33 @@ func (transformer *MessageTransformer) DecryptContent(data []byte, password Symm
 }
 
 // Override
-func (transformer *MessageTransformer) DeserializeContent(data []byte, password SymmetricKey, _ SecureMessage) Content {
+func (transformer *MessageTransformer) DeserializeContent(data []byte, password SymmetricKey, sMsg SecureMessage) Content {
 	compressor := transformer.Compressor
 	dict := password.Map()
-	info := compressor.ExtractContent(data, dict)
-	return ParseContent(info)
+	extracted := data
+	validator := transformer.Validator
+	if validator != nil {
+		var err error
+		if extracted, err = validator.ExtractPackage(data); err != nil {
+			//panic(err.Error())
+			return nil
+		}
+	}
+	info := compressor.ExtractContent(extracted, dict)
+	if info == nil {
+		return nil
+	} else if validator != nil {
+		if err := validator.ValidateContent(info); err != nil {
+			//panic(err.Error())
+			return nil
+		}
+	}
+	content := ParseContent(info)
+	if content != nil && IsCompressedData(data) {
+		// content is compressed by the sender (end to end)
+		transformer.learnCompression(transformer.CompressionDelegate, sMsg.Sender())
+	}
+	return content
 	// NOTICE: check attachment for File/Image/Audio/Video message content
 	//         after deserialize content, this job should be done in subclass
 }
@@ -0,0 +1,292 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"fmt"
+
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// PackageLimits defines the limits for incoming packages
+type PackageLimits struct {
+
+	// MaxPackageSize limits the bytes of a network package
+	MaxPackageSize int
+
+	// MaxKeys limits the entries of 'message.keys'
+	MaxKeys int
+
+	// MaxContentDepth limits the nesting of Array/Forward contents
+	MaxContentDepth int
+
+	// MaxMembers limits the members in group commands
+	MaxMembers int
+}
+
+var DefaultPackageLimits = PackageLimits{
+	MaxPackageSize:  4 * 1024 * 1024,
+	MaxKeys:         2048,
+	MaxContentDepth: 8,
+	MaxMembers:      2048,
+}
+
+// ValidationError describes which field is invalid and why
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", err.Field, err.Reason)
+}
+
+func newValidationError(field, format string, args ...interface{}) error {
+	return &ValidationError{
+		Field:  field,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
+// PackageValidator checks incoming packages before parsing
+type PackageValidator interface {
+
+	// ValidatePackage checks the network package before extracting
+	ValidatePackage(data []byte) error
+
+	// ExtractPackage decompresses the package (or content data) within the size limit
+	//
+	// Returns: decompressed data (or the original data if not compressed)
+	ExtractPackage(data []byte) ([]byte, error)
+
+	// ValidateMessage checks the extracted message info before parsing
+	ValidateMessage(msg StringKeyMap) error
+
+	// ValidateContent checks the decrypted content info before parsing
+	ValidateContent(content StringKeyMap) error
+}
+
+// MessageValidator checks packages with limits, required fields and types
+type MessageValidator struct {
+	//PackageValidator
+
+	Limits PackageLimits
+}
+
+func NewMessageValidator(limits PackageLimits) *MessageValidator {
+	return &MessageValidator{
+		Limits: limits,
+	}
+}
+
+// Override
+func (validator *MessageValidator) ValidatePackage(data []byte) error {
+	size := len(data)
+	if size == 0 {
+		return newValidationError("package", "empty")
+	} else if max := validator.Limits.MaxPackageSize; max > 0 && size > max {
+		return newValidationError("package", "%d bytes exceeds limit %d", size, max)
+	}
+	return nil
+}
+
+// Override
+func (validator *MessageValidator) ExtractPackage(data []byte) ([]byte, error) {
+	if !IsCompressedData(data) {
+		return data, nil
+	}
+	limit := validator.Limits.MaxPackageSize
+	if limit <= 0 || limit > MaxExtractSize {
+		limit = MaxExtractSize
+	}
+	out := gzipExtractWithLimit(data, limit)
+	if out == nil {
+		return nil, newValidationError("package", "failed to extract within limit %d", limit)
+	}
+	return out, nil
+}
+
+// Override
+func (validator *MessageValidator) ValidateMessage(msg StringKeyMap) error {
+	return validator.validateMessage("message", msg)
+}
+
+// Override
+func (validator *MessageValidator) ValidateContent(content StringKeyMap) error {
+	return validator.validateContent("content", content, 0)
+}
+
+func (validator *MessageValidator) validateMessage(path string, msg StringKeyMap) error {
+	if msg == nil {
+		return newValidationError(path, "not a map")
+	}
+	// required fields
+	for _, name := range []string{"sender", "receiver", "data", "signature"} {
+		if err := requireString(path+"."+name, msg[name], true); err != nil {
+			return err
+		}
+	}
+	// optional fields
+	for _, name := range []string{"group", "key"} {
+		if err := requireString(path+"."+name, msg[name], false); err != nil {
+			return err
+		}
+	}
+	if err := requireNumber(path+".time", msg["time"], false); err != nil {
+		return err
+	}
+	if keys, exists := msg["keys"]; exists && keys != nil {
+		table, ok := keys.(StringKeyMap)
+		if !ok {
+			return newValidationError(path+".keys", "not a map")
+		} else if max := validator.Limits.MaxKeys; max > 0 && len(table) > max {
+			return newValidationError(path+".keys", "%d entries exceeds limit %d", len(table), max)
+		}
+		for target, value := range table {
+			if _, ok = value.(string); !ok {
+				return newValidationError(path+".keys."+target, "not a string")
+			}
+		}
+	}
+	return nil
+}
+
+func (validator *MessageValidator) validateContent(path string, content StringKeyMap, depth int) error {
+	if content == nil {
+		return newValidationError(path, "not a map")
+	} else if max := validator.Limits.MaxContentDepth; max > 0 && depth > max {
+		return newValidationError(path, "nesting depth %d exceeds limit %d", depth, max)
+	}
+	// required fields
+	msgType := content["type"]
+	if msgType == nil {
+		return newValidationError(path+".type", "missing")
+	} else if _, ok := msgType.(string); !ok {
+		if err := requireNumber(path+".type", msgType, true); err != nil {
+			return err
+		}
+	}
+	if err := requireNumber(path+".sn", content["sn"], false); err != nil {
+		return err
+	}
+	if err := requireNumber(path+".time", content["time"], false); err != nil {
+		return err
+	}
+	if err := requireString(path+".group", content["group"], false); err != nil {
+		return err
+	}
+	if err := requireString(path+".command", content["command"], false); err != nil {
+		return err
+	}
+	// group members
+	for _, name := range []string{"members", "administrators", "assistants"} {
+		if err := validator.validateMembers(path+"."+name, content[name]); err != nil {
+			return err
+		}
+	}
+	// array content
+	if contents, exists := content["contents"]; exists && contents != nil {
+		array, ok := contents.([]interface{})
+		if !ok {
+			return newValidationError(path+".contents", "not a list")
+		}
+		for i, item := range array {
+			info, _ := item.(StringKeyMap)
+			if err := validator.validateContent(fmt.Sprintf("%s.contents[%d]", path, i), info, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	// forward content
+	if forward, exists := content["forward"]; exists && forward != nil {
+		info, _ := forward.(StringKeyMap)
+		if err := validator.validateMessage(path+".forward", info); err != nil {
+			return err
+		}
+	}
+	if secrets, exists := content["secrets"]; exists && secrets != nil {
+		array, ok := secrets.([]interface{})
+		if !ok {
+			return newValidationError(path+".secrets", "not a list")
+		}
+		for i, item := range array {
+			info, _ := item.(StringKeyMap)
+			if err := validator.validateMessage(fmt.Sprintf("%s.secrets[%d]", path, i), info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (validator *MessageValidator) validateMembers(path string, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	array, ok := value.([]interface{})
+	if !ok {
+		return newValidationError(path, "not a list")
+	} else if max := validator.Limits.MaxMembers; max > 0 && len(array) > max {
+		return newValidationError(path, "%d members exceeds limit %d", len(array), max)
+	}
+	for i, item := range array {
+		if _, ok = item.(string); !ok {
+			return newValidationError(fmt.Sprintf("%s[%d]", path, i), "not a string")
+		}
+	}
+	return nil
+}
+
+func requireString(path string, value interface{}, required bool) error {
+	if value == nil {
+		if required {
+			return newValidationError(path, "missing")
+		}
+		return nil
+	} else if _, ok := value.(string); !ok {
+		return newValidationError(path, "not a string")
+	}
+	return nil
+}
+
+func requireNumber(path string, value interface{}, required bool) error {
+	switch value.(type) {
+	case nil:
+		if required {
+			return newValidationError(path, "missing")
+		}
+		return nil
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return nil
+	default:
+		return newValidationError(path, "not a number")
+	}
+}
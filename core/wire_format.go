@@ -0,0 +1,191 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"sync"
+
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// WireFormat is the serialization format of ReliableMessage
+type WireFormat = string
+
+const (
+	WireFormatUnknown   WireFormat = ""
+	WireFormatLongKeys  WireFormat = "long"   // JSON with full-length keys: "sender", "receiver", ...
+	WireFormatShortKeys WireFormat = "short"  // JSON with short keys: "F", "R", ...
+	WireFormatBinary    WireFormat = "binary" // binary format
+)
+
+// WireFormatDelegate remembers the wire format for each peer
+type WireFormatDelegate interface {
+
+	// GetWireFormat returns the format for sending message to the peer
+	//
+	// Parameters:
+	//   - peer - Receiver ID (or station ID)
+	// Returns: pinned format, or the format last received from the peer, or the default format
+	GetWireFormat(peer ID) WireFormat
+
+	// SetWireFormat remembers the format last received from the peer
+	//
+	// Parameters:
+	//   - format - Format of received message
+	//   - peer   - Sender ID
+	SetWireFormat(format WireFormat, peer ID)
+}
+
+// DetectWireFormat checks the format of serialized message
+//
+// Parameters:
+//   - data - Serialized message (decompressed)
+//
+// Returns: message format
+func DetectWireFormat(data []byte) WireFormat {
+	if IsBinaryMessage(data) {
+		return WireFormatBinary
+	} else if len(data) == 0 || data[0] != '{' {
+		return WireFormatUnknown
+	}
+	return DetectWireFormatOfMap(jsonDecode(data))
+}
+
+// DetectWireFormatOfMap checks the keys of decoded message (before restoring short keys)
+func DetectWireFormatOfMap(info StringKeyMap) WireFormat {
+	if info == nil {
+		return WireFormatUnknown
+	} else if _, exists := info["sender"]; exists {
+		return WireFormatLongKeys
+	} else if _, exists = info["F"]; exists {
+		return WireFormatShortKeys
+	}
+	return WireFormatUnknown
+}
+
+// MaxLearnedWireFormats limits the number of peers remembered by the negotiator
+var MaxLearnedWireFormats = 4096
+
+// WireFormatNegotiator is a memory cache for peers' wire formats
+//
+//	For a client, all messages are sent to its station (next hop),
+//	so after SetStation() the station's format is used for all receivers.
+type WireFormatNegotiator struct {
+	//WireFormatDelegate
+
+	// DefaultFormat is used for unknown peers
+	DefaultFormat WireFormat
+
+	mutex   sync.RWMutex
+	learned map[string]WireFormat
+	pinned  map[string]WireFormat
+	station ID
+}
+
+func NewWireFormatNegotiator(defaultFormat WireFormat) *WireFormatNegotiator {
+	return &WireFormatNegotiator{
+		DefaultFormat: defaultFormat,
+		learned:       make(map[string]WireFormat),
+		pinned:        make(map[string]WireFormat),
+	}
+}
+
+// Override
+func (negotiator *WireFormatNegotiator) GetWireFormat(peer ID) WireFormat {
+	negotiator.mutex.RLock()
+	defer negotiator.mutex.RUnlock()
+	if station := negotiator.station; station != nil {
+		// all messages go through the station
+		peer = station
+	}
+	identifier := peer.String()
+	if format, ok := negotiator.pinned[identifier]; ok {
+		return format
+	} else if format, ok = negotiator.learned[identifier]; ok {
+		return format
+	}
+	return negotiator.DefaultFormat
+}
+
+// Override
+func (negotiator *WireFormatNegotiator) SetWireFormat(format WireFormat, peer ID) {
+	if format == WireFormatUnknown {
+		return
+	}
+	negotiator.mutex.Lock()
+	defer negotiator.mutex.Unlock()
+	if station := negotiator.station; station != nil {
+		// all messages come from the station
+		peer = station
+	}
+	identifier := peer.String()
+	if _, exists := negotiator.learned[identifier]; !exists && len(negotiator.learned) >= MaxLearnedWireFormats {
+		// evict one peer to keep the cache bounded
+		for key := range negotiator.learned {
+			delete(negotiator.learned, key)
+			break
+		}
+	}
+	negotiator.learned[identifier] = format
+}
+
+// SetStation sets the next hop for all outgoing messages (client only),
+// nil to use the receiver's format
+func (negotiator *WireFormatNegotiator) SetStation(station ID) {
+	negotiator.mutex.Lock()
+	defer negotiator.mutex.Unlock()
+	negotiator.station = station
+}
+
+func (negotiator *WireFormatNegotiator) Station() ID {
+	negotiator.mutex.RLock()
+	defer negotiator.mutex.RUnlock()
+	return negotiator.station
+}
+
+// PinWireFormat fixes the format for the peer (e.g.: a station),
+// the received messages will not change it;
+// for a client, call SetStation() too, so the pinned format is used for all receivers
+func (negotiator *WireFormatNegotiator) PinWireFormat(format WireFormat, peer ID) {
+	negotiator.mutex.Lock()
+	defer negotiator.mutex.Unlock()
+	if format == WireFormatUnknown {
+		delete(negotiator.pinned, peer.String())
+	} else {
+		negotiator.pinned[peer.String()] = format
+	}
+}
+
+// UnpinWireFormat removes the pinned format for the peer
+func (negotiator *WireFormatNegotiator) UnpinWireFormat(peer ID) {
+	negotiator.PinWireFormat(WireFormatUnknown, peer)
+}
@@ -0,0 +1,194 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+	. "github.com/dimchat/sdk-go/core"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+// GroupMessageRelayer sends messages split by the group assistant to members,
+// should be implemented by the assistant's messenger
+type GroupMessageRelayer interface {
+
+	// RelayMessage sends the message to its receiver
+	//
+	// Parameters:
+	//   - rMsg - Message for one member
+	// Returns: false on failure
+	RelayMessage(rMsg ReliableMessage) bool
+}
+
+/**
+ *  CPU for ForwardContent with group assistant
+ *
+ *      1. member -> assistant: forward group message (receiver is the group)
+ *      2. assistant -> members: forward split messages (receiver is the member, 'group' kept)
+ */
+
+type GroupForwardContentProcessor struct {
+	*ForwardContentProcessor
+}
+
+// Override
+func (cpu *GroupForwardContentProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
+	forwardContent, ok := content.(ForwardContent)
+	if !ok {
+		//panic("forward content error")
+		return nil
+	}
+	group := forwardContent.Group()
+	if group == nil {
+		// normal forward content
+		return cpu.ForwardContentProcessor.ProcessContent(content, rMsg)
+	}
+	assistants := GetGroupAssistants(cpu.Facebook, group)
+	if containsID(assistants, rMsg.Receiver()) {
+		// I'm the assistant, split for members
+		return cpu.relayGroupMessages(forwardContent, group, rMsg)
+	} else if containsID(assistants, rMsg.Sender()) {
+		// group message split by the assistant
+		return cpu.receiveGroupMessages(forwardContent, group, rMsg)
+	}
+	return cpu.ForwardContentProcessor.ProcessContent(content, rMsg)
+}
+
+// assistant side
+func (cpu *GroupForwardContentProcessor) relayGroupMessages(content ForwardContent, group ID, rMsg ReliableMessage) []Content {
+	messenger := cpu.Messenger
+	relayer, ok := messenger.(GroupMessageRelayer)
+	if !ok {
+		return cpu.RespondReceipt("Group relay not supported.", rMsg.Envelope(), content, nil)
+	}
+	members := cpu.Facebook.GetMembers(group)
+	if !containsID(members, rMsg.Sender()) {
+		return cpu.RespondReceipt("Permission denied.", rMsg.Envelope(), content, StringKeyMap{
+			"template": "Not a member of group: ${gid}.",
+			"replacements": StringKeyMap{
+				"gid": group.String(),
+			},
+		})
+	}
+	bot := rMsg.Receiver()
+	relayed := 0
+	missed := 0
+	var msg ReliableMessage
+	for _, item := range content.SecretMessages() {
+		if !item.Receiver().Equal(group) || !item.Sender().Equal(rMsg.Sender()) {
+			//panic("not a group message from the sender")
+			continue
+		}
+		for _, member := range members {
+			if member.Equal(item.Sender()) || member.Equal(bot) {
+				// skip the sender & myself
+				continue
+			}
+			msg = cpu.packRelayMessage(item, member, group, bot)
+			if msg == nil || !relayer.RelayMessage(msg) {
+				missed++
+				continue
+			}
+			relayed++
+		}
+	}
+	return cpu.RespondReceipt("Group message relayed.", rMsg.Envelope(), content, StringKeyMap{
+		"template": "Group message relayed: ${gid}, ${relayed} member(s), ${missed} missed.",
+		"replacements": StringKeyMap{
+			"gid":     group.String(),
+			"relayed": relayed,
+			"missed":  missed,
+		},
+	})
+}
+
+func (cpu *GroupForwardContentProcessor) packRelayMessage(rMsg ReliableMessage, member, group, bot ID) ReliableMessage {
+	item := SplitGroupMessage(rMsg, member)
+	if item == nil {
+		// key for this member not found
+		return nil
+	}
+	messenger := cpu.Messenger
+	envelope := CreateEnvelope(bot, member, nil)
+	content := NewForwardMessage(item)
+	content.SetGroup(group)
+	iMsg := CreateInstantMessage(envelope, content)
+	sMsg := messenger.EncryptMessage(iMsg)
+	if sMsg == nil {
+		// member's visa not found
+		return nil
+	}
+	return messenger.SignMessage(sMsg)
+}
+
+// member side
+func (cpu *GroupForwardContentProcessor) receiveGroupMessages(content ForwardContent, group ID, rMsg ReliableMessage) []Content {
+	depth := nestingDepth(content, rMsg)
+	if depth >= MaxNestingDepth {
+		return cpu.respondNestingTooDeep(depth+1, content, rMsg)
+	}
+	messenger := cpu.Messenger
+	receiver := rMsg.Receiver()
+	for _, item := range content.SecretMessages() {
+		if !item.Receiver().Equal(receiver) || !group.Equal(ParseID(item.Get("group"))) {
+			//panic("not a group message split for me")
+			continue
+		}
+		// NOTICE: responses for group message should not be sent back to the assistant
+		StripLocalKeys(item)
+		sMsg := messenger.VerifyMessage(item)
+		if sMsg == nil {
+			//panic("relayed message not verified")
+			continue
+		}
+		chain := appendProvenance(rMsg, item)
+		sMsg.Set(provenanceKey, chain)
+		item.Set(provenanceKey, chain)
+		item.Set(nestingDepthKey, depth+1)
+		messenger.ProcessSecureMessage(sMsg, item)
+		item.Remove(provenanceKey)
+		item.Remove(nestingDepthKey)
+	}
+	return nil
+}
+
+func containsID(array []ID, did ID) bool {
+	for _, item := range array {
+		if item.Equal(did) {
+			return true
+		}
+	}
+	return false
+}
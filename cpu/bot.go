@@ -0,0 +1,405 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/sdk-go/dkd"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+/**
+ *  Bot Commands
+ *  ~~~~~~~~~~~~
+ *
+ *      text content: "/command arg1 arg2 ..."
+ *
+ *  Usage:
+ *
+ *      bot := NewBotCommands()
+ *      bot.Register(&BotCommand{
+ *          Name:        "echo",
+ *          Description: "Repeat the words",
+ *          Arguments:   []BotArgument{{Name: "words", Type: BotArgString}},
+ *          Handler: func(ctx context.Context, args *BotArgs, rMsg ReliableMessage) (string, error) {
+ *              return args.String("words"), nil
+ *          },
+ *      })
+ *      RegisterContentProcessor(ContentType.TEXT, bot.Constructor())
+ */
+
+type BotArgType int
+
+const (
+	BotArgString BotArgType = iota
+	BotArgInt
+	BotArgFloat
+	BotArgBool
+	BotArgID
+)
+
+func (t BotArgType) String() string {
+	switch t {
+	case BotArgInt:
+		return "int"
+	case BotArgFloat:
+		return "float"
+	case BotArgBool:
+		return "bool"
+	case BotArgID:
+		return "ID"
+	default:
+		return "string"
+	}
+}
+
+// BotArgument describes one argument of the command,
+// the last string argument takes all the rest words
+type BotArgument struct {
+	Name     string
+	Type     BotArgType
+	Optional bool
+}
+
+// BotArgs contains parsed arguments
+type BotArgs struct {
+	Command string
+	Sender  ID
+	Content Content
+
+	values map[string]interface{}
+}
+
+func (args *BotArgs) Has(name string) bool {
+	_, ok := args.values[name]
+	return ok
+}
+
+func (args *BotArgs) String(name string) string {
+	value, _ := args.values[name].(string)
+	return value
+}
+
+func (args *BotArgs) Int(name string) int64 {
+	value, _ := args.values[name].(int64)
+	return value
+}
+
+func (args *BotArgs) Float(name string) float64 {
+	value, _ := args.values[name].(float64)
+	return value
+}
+
+func (args *BotArgs) Bool(name string) bool {
+	value, _ := args.values[name].(bool)
+	return value
+}
+
+func (args *BotArgs) ID(name string) ID {
+	value, _ := args.values[name].(ID)
+	return value
+}
+
+// BotCommandHandler handles the command and returns the reply text
+// (empty string for no reply)
+type BotCommandHandler func(ctx context.Context, args *BotArgs, rMsg ReliableMessage) (string, error)
+
+type BotCommand struct {
+	Name        string
+	Description string
+	Arguments   []BotArgument
+
+	// Allow checks the sender's permission (nil to allow everyone)
+	Allow func(sender ID) bool
+
+	Handler BotCommandHandler
+}
+
+// Usage returns "/name <arg:type> [arg:type]"
+func (cmd *BotCommand) Usage() string {
+	var sb strings.Builder
+	sb.WriteString("/")
+	sb.WriteString(cmd.Name)
+	for _, arg := range cmd.Arguments {
+		if arg.Optional {
+			sb.WriteString(fmt.Sprintf(" [%s:%s]", arg.Name, arg.Type))
+		} else {
+			sb.WriteString(fmt.Sprintf(" <%s:%s>", arg.Name, arg.Type))
+		}
+	}
+	return sb.String()
+}
+
+func (cmd *BotCommand) isAllowed(sender ID) bool {
+	return cmd.Allow == nil || cmd.Allow(sender)
+}
+
+// parseArguments converts words to typed values
+func (cmd *BotCommand) parseArguments(words []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(cmd.Arguments))
+	count := len(cmd.Arguments)
+	for i, arg := range cmd.Arguments {
+		if i >= len(words) {
+			if !arg.Optional {
+				return nil, fmt.Errorf("missing argument: %s", arg.Name)
+			}
+			continue
+		}
+		word := words[i]
+		if i == count-1 && arg.Type == BotArgString {
+			// the last string argument takes all the rest words
+			word = strings.Join(words[i:], " ")
+		}
+		switch arg.Type {
+		case BotArgInt:
+			number, err := strconv.ParseInt(word, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("argument %s is not an integer: %s", arg.Name, word)
+			}
+			values[arg.Name] = number
+		case BotArgFloat:
+			number, err := strconv.ParseFloat(word, 64)
+			if err != nil {
+				return nil, fmt.Errorf("argument %s is not a number: %s", arg.Name, word)
+			}
+			values[arg.Name] = number
+		case BotArgBool:
+			flag, err := strconv.ParseBool(word)
+			if err != nil {
+				return nil, fmt.Errorf("argument %s is not a bool: %s", arg.Name, word)
+			}
+			values[arg.Name] = flag
+		case BotArgID:
+			did := ParseID(word)
+			if did == nil {
+				return nil, fmt.Errorf("argument %s is not an ID: %s", arg.Name, word)
+			}
+			values[arg.Name] = did
+		default:
+			values[arg.Name] = word
+		}
+	}
+	if len(words) > count && (count == 0 || cmd.Arguments[count-1].Type != BotArgString) {
+		return nil, fmt.Errorf("too many arguments")
+	}
+	return values, nil
+}
+
+// BotCommands is the registry of bot commands
+type BotCommands struct {
+	mutex    sync.RWMutex
+	commands map[string]*BotCommand
+
+	// Title is the first line of help text
+	Title string
+}
+
+func NewBotCommands() *BotCommands {
+	return &BotCommands{
+		commands: make(map[string]*BotCommand, 8),
+		Title:    "Commands:",
+	}
+}
+
+// Register adds (or overrides) a command
+func (bot *BotCommands) Register(cmd *BotCommand) {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+	bot.commands[strings.ToLower(cmd.Name)] = cmd
+}
+
+func (bot *BotCommands) GetCommand(name string) *BotCommand {
+	bot.mutex.RLock()
+	defer bot.mutex.RUnlock()
+	return bot.commands[strings.ToLower(name)]
+}
+
+// Help lists the commands which the sender can use
+func (bot *BotCommands) Help(sender ID) string {
+	bot.mutex.RLock()
+	names := make([]string, 0, len(bot.commands))
+	for name, cmd := range bot.commands {
+		if cmd.isAllowed(sender) {
+			names = append(names, name)
+		}
+	}
+	bot.mutex.RUnlock()
+	sort.Strings(names)
+	lines := make([]string, 0, len(names)+2)
+	lines = append(lines, bot.Title, "/help - Show this list")
+	var cmd *BotCommand
+	for _, name := range names {
+		cmd = bot.GetCommand(name)
+		if cmd == nil {
+			continue
+		} else if cmd.Description == "" {
+			lines = append(lines, cmd.Usage())
+		} else {
+			lines = append(lines, cmd.Usage()+" - "+cmd.Description)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Constructor returns the processor constructor for registry
+func (bot *BotCommands) Constructor() ContentProcessorConstructor {
+	return func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewBotCommandProcessor(facebook, messenger, bot)
+	}
+}
+
+// SplitCommandLine parses "/name arg1 "arg 2"" to name & words
+//
+// Returns: command name (empty if the text is not a command), words
+func SplitCommandLine(text string) (string, []string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", nil
+	}
+	var words []string
+	var sb strings.Builder
+	inQuote := false
+	hasWord := false
+	for _, ch := range text[1:] {
+		switch {
+		case ch == '"':
+			inQuote = !inQuote
+			hasWord = true
+		case !inQuote && (ch == ' ' || ch == '\t' || ch == '\n'):
+			if hasWord {
+				words = append(words, sb.String())
+				sb.Reset()
+				hasWord = false
+			}
+		default:
+			sb.WriteRune(ch)
+			hasWord = true
+		}
+	}
+	if hasWord {
+		words = append(words, sb.String())
+	}
+	if len(words) == 0 {
+		return "", nil
+	}
+	name := words[0]
+	if pos := strings.Index(name, "@"); pos > 0 {
+		// "/command@bot"
+		name = name[:pos]
+	}
+	return name, words[1:]
+}
+
+/**
+ *  CPU for bot commands in TextContent
+ */
+
+type BotCommandProcessor struct {
+	*BaseContentProcessor
+
+	Commands *BotCommands
+}
+
+func NewBotCommandProcessor(facebook Facebook, messenger Messenger, bot *BotCommands) *BotCommandProcessor {
+	return &BotCommandProcessor{
+		BaseContentProcessor: NewBaseContentProcessor(facebook, messenger),
+		Commands:             bot,
+	}
+}
+
+// Override
+func (cpu *BotCommandProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
+	return cpu.ProcessContentWithContext(context.Background(), content, rMsg)
+}
+
+// Override
+func (cpu *BotCommandProcessor) ProcessContentWithContext(ctx context.Context, content Content, rMsg ReliableMessage) []Content {
+	var text string
+	if textContent, ok := content.(TextContent); ok {
+		text = textContent.Text()
+	} else {
+		text = content.GetString("text", "")
+	}
+	name, words := SplitCommandLine(text)
+	if name == "" {
+		// not a command, ignore it
+		return nil
+	}
+	sender := rMsg.Sender()
+	bot := cpu.Commands
+	if strings.EqualFold(name, "help") {
+		return cpu.respondText(bot.Help(sender), content)
+	}
+	cmd := bot.GetCommand(name)
+	if cmd == nil || cmd.Handler == nil {
+		return cpu.respondText(fmt.Sprintf("Unknown command: /%s, try /help", name), content)
+	} else if !cmd.isAllowed(sender) {
+		return cpu.respondText(fmt.Sprintf("Permission denied: /%s", name), content)
+	}
+	values, err := cmd.parseArguments(words)
+	if err != nil {
+		return cpu.respondText(fmt.Sprintf("%s\nUsage: %s", err.Error(), cmd.Usage()), content)
+	}
+	args := &BotArgs{
+		Command: cmd.Name,
+		Sender:  sender,
+		Content: content,
+		values:  values,
+	}
+	ctx = NewHandlerContext(ctx, cpu.Facebook, cpu.Messenger)
+	reply, err := cmd.Handler(ctx, args, rMsg)
+	if err != nil {
+		return cpu.respondText(fmt.Sprintf("Command failed: /%s, %s", name, err.Error()), content)
+	}
+	return cpu.respondText(reply, content)
+}
+
+// protected
+func (cpu *BotCommandProcessor) respondText(text string, content Content) []Content {
+	if text == "" {
+		return nil
+	}
+	res := NewTextContent(text)
+	if group := content.Group(); group != nil {
+		res.SetGroup(group)
+	}
+	return []Content{res}
+}
@@ -0,0 +1,338 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+// CONTACT is the command for establishing contacts
+//
+//	data format: {
+//	    "type" : i2s(0x88),
+//	    "sn"   : 123,
+//
+//	    "command"   : "contact",
+//	    "action"    : "request",  // "accept", "decline", "remove"
+//	    "message"   : "Hi, I'm ...",
+//	    "meta"      : {...},      // sender's meta (for request & accept)
+//	    "documents" : [...]       // sender's visa (for request & accept)
+//	}
+const CONTACT = "contact"
+
+const (
+	ContactRequest = "request"
+	ContactAccept  = "accept"
+	ContactDecline = "decline"
+	ContactRemove  = "remove"
+)
+
+func NewContactCommand(action string, meta Meta, documents []Document) Command {
+	content := NewBaseCommand(nil, "", CONTACT)
+	content.Set("action", action)
+	if meta != nil {
+		content.Set("meta", meta.Map())
+	}
+	if len(documents) > 0 {
+		array := make([]interface{}, 0, len(documents))
+		for _, doc := range documents {
+			array = append(array, doc.Map())
+		}
+		content.Set("documents", array)
+	}
+	return content
+}
+
+// NewContactCommandForUser creates contact command with the user's meta & documents
+func NewContactCommandForUser(action string, uid ID, facebook Facebook) Command {
+	if action == ContactRequest || action == ContactAccept {
+		return NewContactCommand(action, facebook.GetMeta(uid), facebook.GetDocuments(uid))
+	}
+	return NewContactCommand(action, nil, nil)
+}
+
+// ContactDelegate keeps the writable contact list & pending requests
+type ContactDelegate interface {
+
+	// AddContact appends the contact to the user's contact list
+	AddContact(contact ID, user ID) bool
+
+	// RemoveContact removes the contact from the user's contact list
+	RemoveContact(contact ID, user ID) bool
+
+	// IsContactRequested checks whether the user has sent request to the contact
+	IsContactRequested(contact ID, user ID) bool
+
+	// SaveContactRequested records the request sent by the user to the contact
+	SaveContactRequested(contact ID, user ID) bool
+
+	// SaveContactRequest stores the incoming request for the user's decision
+	SaveContactRequest(request Command, sender ID, user ID) bool
+
+	// RemoveContactRequest clears the pending requests between the user & the contact
+	RemoveContactRequest(contact ID, user ID)
+}
+
+var sharedContactDelegate ContactDelegate = nil
+
+func SetContactDelegate(delegate ContactDelegate) {
+	sharedContactDelegate = delegate
+}
+
+func GetContactDelegate() ContactDelegate {
+	return sharedContactDelegate
+}
+
+//
+//  Local side
+//
+
+// RequestContact records the pending request, and returns the command to be sent to the contact
+func RequestContact(contact ID, user ID, facebook Facebook) Command {
+	delegate := GetContactDelegate()
+	if delegate == nil {
+		//panic("contact delegate not set")
+		return nil
+	} else if !delegate.SaveContactRequested(contact, user) {
+		//panic("failed to save contact request")
+		return nil
+	}
+	return NewContactCommandForUser(ContactRequest, user, facebook)
+}
+
+// AcceptContact adds the requester into the contact list,
+// and returns the command to be sent back to the contact
+func AcceptContact(contact ID, user ID, facebook Facebook) Command {
+	delegate := GetContactDelegate()
+	if delegate == nil {
+		//panic("contact delegate not set")
+		return nil
+	} else if !delegate.AddContact(contact, user) {
+		//panic("failed to add contact")
+		return nil
+	}
+	delegate.RemoveContactRequest(contact, user)
+	return NewContactCommandForUser(ContactAccept, user, facebook)
+}
+
+// DeclineContact clears the pending request,
+// and returns the command to be sent back to the contact
+func DeclineContact(contact ID, user ID) Command {
+	delegate := GetContactDelegate()
+	if delegate == nil {
+		//panic("contact delegate not set")
+		return nil
+	}
+	delegate.RemoveContactRequest(contact, user)
+	return NewContactCommand(ContactDecline, nil, nil)
+}
+
+/**
+ *  CPU for ContactCommand
+ */
+
+type ContactCommandProcessor struct {
+	*DocumentCommandProcessor
+}
+
+// Override
+func (cpu *ContactCommandProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
+	command, ok := content.(Command)
+	if !ok {
+		//panic("contact command error")
+		return nil
+	}
+	delegate := GetContactDelegate()
+	if delegate == nil {
+		//panic("contact delegate not set")
+		return nil
+	}
+	sender := rMsg.Sender()
+	envelope := rMsg.Envelope()
+	// 1. contact command must be sent to local user directly
+	user := cpu.Facebook.SelectUser(rMsg.Receiver())
+	if user == nil || rMsg.Receiver().IsBroadcast() || command.Group() != nil {
+		return cpu.RespondReceipt("Contact command error.", envelope, content, nil)
+	}
+	action := command.GetString("action", "")
+	switch action {
+	case ContactRequest:
+		if errors := cpu.saveContactInfo(command, sender, envelope); errors != nil {
+			return errors
+		}
+		return cpu.processRequest(command, sender, user, envelope, delegate)
+	case ContactAccept:
+		if errors := cpu.saveContactInfo(command, sender, envelope); errors != nil {
+			return errors
+		}
+		return cpu.processAccept(command, sender, user, envelope, delegate)
+	case ContactDecline:
+		delegate.RemoveContactRequest(sender, user)
+		return nil
+	case ContactRemove:
+		delegate.RemoveContactRequest(sender, user)
+		delegate.RemoveContact(sender, user)
+		return nil
+	}
+	return cpu.RespondReceipt("Contact command error.", envelope, content, StringKeyMap{
+		"template": "Contact action not support: ${action}.",
+		"replacements": StringKeyMap{
+			"action": action,
+		},
+	})
+}
+
+// protected
+func (cpu *ContactCommandProcessor) processRequest(command Command, sender, user ID, envelope Envelope, delegate ContactDelegate) []Content {
+	facebook := cpu.Facebook
+	if containsID(facebook.GetContacts(user), sender) || delegate.IsContactRequested(sender, user) {
+		// already a contact, or both sides requested,
+		// accept it automatically
+		delegate.RemoveContactRequest(sender, user)
+		delegate.AddContact(sender, user)
+		return []Content{
+			NewContactCommandForUser(ContactAccept, user, facebook),
+		}
+	}
+	// waiting for the user's decision
+	delegate.SaveContactRequest(command, sender, user)
+	return cpu.RespondReceipt("Contact request received.", envelope, command, nil)
+}
+
+// protected
+func (cpu *ContactCommandProcessor) processAccept(command Command, sender, user ID, envelope Envelope, delegate ContactDelegate) []Content {
+	if !delegate.IsContactRequested(sender, user) {
+		return cpu.RespondReceipt("Permission denied.", envelope, command, StringKeyMap{
+			"template": "Contact not requested: ${did}.",
+			"replacements": StringKeyMap{
+				"did": sender.String(),
+			},
+		})
+	}
+	delegate.RemoveContactRequest(sender, user)
+	delegate.AddContact(sender, user)
+	return nil
+}
+
+// saveContactInfo saves meta & documents of the sender
+func (cpu *ContactCommandProcessor) saveContactInfo(command Command, sender ID, envelope Envelope) []Content {
+	facebook := cpu.Facebook
+	meta := ParseMeta(command.Get("meta"))
+	if meta == nil {
+		meta = facebook.GetMeta(sender)
+		if meta == nil {
+			return cpu.RespondReceipt("Meta not found.", envelope, command, StringKeyMap{
+				"template": "Meta not found: ${did}.",
+				"replacements": StringKeyMap{
+					"did": sender.String(),
+				},
+			})
+		}
+	} else if !cpu.checkMeta(meta, sender) || !facebook.SaveMeta(meta, sender) {
+		return cpu.RespondReceipt("Meta not accepted.", envelope, command, StringKeyMap{
+			"template": "Meta not accepted: ${did}.",
+			"replacements": StringKeyMap{
+				"did": sender.String(),
+			},
+		})
+	}
+	array, _ := command.Get("documents").([]interface{})
+	var doc Document
+	for _, item := range array {
+		doc = ParseDocument(item)
+		if doc == nil || !cpu.checkDocument(doc, meta, sender) {
+			//panic("document error")
+			continue
+		}
+		// document expired?
+		facebook.SaveDocument(doc, sender)
+	}
+	return nil
+}
+
+/**
+ *  Stranger Gate
+ *  ~~~~~~~~~~~~~
+ *
+ *  Policy store which allows contacts of the local user only
+ */
+
+type ContactPolicyStore struct {
+	Facebook Facebook
+
+	// User is the local user
+	User ID
+
+	// Blocked is the block list (optional)
+	Blocked PolicyStore
+}
+
+// Override
+func (store *ContactPolicyStore) IsBlocked(did ID) bool {
+	blocked := store.Blocked
+	return blocked != nil && blocked.IsBlocked(did)
+}
+
+// Override
+func (store *ContactPolicyStore) IsAllowed(did ID) bool {
+	if did.Equal(store.User) {
+		return true
+	}
+	return containsID(store.Facebook.GetContacts(store.User), did)
+}
+
+// NewContactGatedPolicy creates content policy which accepts
+// handshake/meta/documents/contact commands only from strangers
+func NewContactGatedPolicy(store *ContactPolicyStore) *ContentPolicy {
+	policy := NewContentPolicy(store)
+	policy.AddRule(&PolicyRule{
+		Name:    "blocked",
+		Subject: BlockedSender,
+		Action:  PolicyDrop,
+	})
+	policy.AddRule(&PolicyRule{
+		Name:     "stranger-commands",
+		Subject:  StrangerSender,
+		Commands: []string{HANDSHAKE, META, DOCUMENTS, CONTACT},
+		Action:   PolicyAccept,
+	})
+	policy.AddRule(&PolicyRule{
+		Name:    "stranger",
+		Subject: StrangerSender,
+		Action:  PolicyReject,
+	})
+	return policy
+}
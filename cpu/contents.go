@@ -34,6 +34,7 @@ import (
 	. "github.com/dimchat/core-go/dkd"
 	. "github.com/dimchat/core-go/protocol"
 	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/sdk-go/core"
 )
 
 /**
@@ -45,24 +46,61 @@ type ForwardContentProcessor struct {
 }
 
 // Override
-func (cpu *ForwardContentProcessor) ProcessContent(content Content, _ ReliableMessage) []Content {
+func (cpu *ForwardContentProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
 	forwardContent, ok := content.(ForwardContent)
 	if !ok {
 		//panic("forward content error")
 		return nil
 	}
+	// check nesting depth
+	depth := nestingDepth(content, rMsg)
+	if depth >= MaxNestingDepth {
+		return cpu.respondNestingTooDeep(depth+1, content, rMsg)
+	}
+	// check duplicated messages in this processing tree
+	seen, isRoot := nestingSeen(rMsg)
+	if isRoot {
+		seen[nestingMessageKey(rMsg)] = true
+		defer rMsg.Remove(nestingSeenKey)
+	}
 	secrets := forwardContent.SecretMessages()
+	var key string
+	for _, item := range secrets {
+		key = nestingMessageKey(item)
+		if seen[key] {
+			return cpu.respondNestingLoop(content, rMsg)
+		}
+		seen[key] = true
+	}
 	size := len(secrets)
 	if size < 1 {
 		size = 1
 	}
-	responses := make([]Content, size)
+	// start empty, one response is appended for each item
+	responses := make([]Content, 0, size)
 	// call messenger to process it
 	messenger := cpu.Messenger
 	var res Content
 	var results []ReliableMessage
 	for _, item := range secrets {
-		results = messenger.ProcessReliableMessage(item)
+		// verify the forwarded message independently
+		StripLocalKeys(item)
+		sMsg := messenger.VerifyMessage(item)
+		if sMsg == nil {
+			responses = append(responses, cpu.respondNotVerified(item, content, rMsg)...)
+			continue
+		}
+		// the verified message is a copy, set the chain on both,
+		// so it will be copied into the decrypted InstantMessage
+		chain := appendProvenance(rMsg, item)
+		sMsg.Set(provenanceKey, chain)
+		item.Set(provenanceKey, chain)
+		item.Set(nestingDepthKey, depth+1)
+		item.Set(nestingSeenKey, seen)
+		results = cpu.processVerifiedMessage(sMsg, item)
+		item.Remove(provenanceKey)
+		item.Remove(nestingDepthKey)
+		item.Remove(nestingSeenKey)
 		if results == nil {
 			res = NewForwardMessages([]ReliableMessage{})
 		} else if len(results) == 1 {
@@ -75,6 +113,27 @@ func (cpu *ForwardContentProcessor) ProcessContent(content Content, _ ReliableMe
 	return responses
 }
 
+// protected
+func (cpu *ForwardContentProcessor) processVerifiedMessage(sMsg SecureMessage, rMsg ReliableMessage) []ReliableMessage {
+	messenger := cpu.Messenger
+	// the forwarded message has been verified already, process it directly
+	responses := messenger.ProcessSecureMessage(sMsg, rMsg)
+	if len(responses) == 0 {
+		// nothing to respond
+		return nil
+	}
+	messages := make([]ReliableMessage, 0, len(responses))
+	for _, res := range responses {
+		msg := messenger.SignMessage(res)
+		if msg == nil {
+			// should not happen
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
 /**
  *  CPU for ArrayContent
  */
@@ -90,18 +149,26 @@ func (cpu *ArrayContentProcessor) ProcessContent(content Content, rMsg ReliableM
 		//panic("array content error")
 		return nil
 	}
+	// check nesting depth
+	depth := nestingDepth(content, rMsg)
+	if depth >= MaxNestingDepth {
+		return cpu.respondNestingTooDeep(depth+1, content, rMsg)
+	}
 	array := arrayContent.Contents()
 	size := len(array)
 	if size < 1 {
 		size = 1
 	}
-	responses := make([]Content, size)
+	// start empty, one response is appended for each item
+	responses := make([]Content, 0, size)
 	// call messenger to process it
 	messenger := cpu.Messenger
 	var res Content
 	var results []Content
 	for _, item := range array {
+		item.Set(nestingDepthKey, nestingDepthOf(content)+1)
 		results = messenger.ProcessContent(item, rMsg)
+		item.Remove(nestingDepthKey)
 		if results == nil {
 			res = NewArrayContent([]Content{})
 		} else if len(results) == 1 {
@@ -34,6 +34,7 @@ import (
 	. "github.com/dimchat/core-go/protocol"
 	. "github.com/dimchat/dkd-go/protocol"
 	. "github.com/dimchat/sdk-go/dkd"
+	. "github.com/dimchat/sdk-go/msg"
 	. "github.com/dimchat/sdk-go/sdk"
 )
 
@@ -54,41 +55,22 @@ func NewBaseContentProcessorCreator(facebook Facebook, messenger Messenger) *Bas
 
 // Override
 func (creator *BaseContentProcessorCreator) CreateContentProcessor(msgType MessageType) ContentProcessor {
-	switch msgType {
-	// forward content
-	case ContentType.FORWARD:
-		return NewForwardContentProcessor(creator.Facebook, creator.Messenger)
-	// array content
-	case ContentType.ARRAY:
-		return NewArrayContentProcessor(creator.Facebook, creator.Messenger)
-	// default commands
-	case ContentType.COMMAND:
-		return NewBaseCommandProcessor(creator.Facebook, creator.Messenger)
-	// default contents
-	case ContentType.ANY:
-		// must return a default processor for unknown type
-		return NewBaseContentProcessor(creator.Facebook, creator.Messenger)
-	// unknown
-	default:
+	fn := GetContentProcessorRegistry().GetContentConstructor(msgType)
+	if fn == nil {
 		//panic("unsupported content type")
 		return nil
 	}
+	return fn(creator.Facebook, creator.Messenger)
 }
 
 // Override
 func (creator *BaseContentProcessorCreator) CreateCommandProcessor(_ MessageType, cmdName string) ContentProcessor {
-	switch cmdName {
-	// meta command
-	case META:
-		return NewMetaCommandProcessor(creator.Facebook, creator.Messenger)
-	// documents command
-	case DOCUMENTS:
-		return NewDocumentCommandProcessor(creator.Facebook, creator.Messenger)
-	// unknown
-	default:
+	fn := GetContentProcessorRegistry().GetCommandConstructor(cmdName)
+	if fn == nil {
 		//panic("unsupported command: " + cmdName)
 		return nil
 	}
+	return fn(creator.Facebook, creator.Messenger)
 }
 
 //
@@ -113,12 +95,24 @@ func NewForwardContentProcessor(facebook Facebook, messenger Messenger) *Forward
 	}
 }
 
+func NewGroupForwardContentProcessor(facebook Facebook, messenger Messenger) *GroupForwardContentProcessor {
+	return &GroupForwardContentProcessor{
+		ForwardContentProcessor: NewForwardContentProcessor(facebook, messenger),
+	}
+}
+
 func NewArrayContentProcessor(facebook Facebook, messenger Messenger) *ArrayContentProcessor {
 	return &ArrayContentProcessor{
 		BaseContentProcessor: NewBaseContentProcessor(facebook, messenger),
 	}
 }
 
+func NewCustomizedContentProcessor(facebook Facebook, messenger Messenger) *CustomizedContentProcessor {
+	return &CustomizedContentProcessor{
+		BaseContentProcessor: NewBaseContentProcessor(facebook, messenger),
+	}
+}
+
 func NewMetaCommandProcessor(facebook Facebook, messenger Messenger) *MetaCommandProcessor {
 	return &MetaCommandProcessor{
 		BaseCommandProcessor: NewBaseCommandProcessor(facebook, messenger),
@@ -131,6 +125,30 @@ func NewDocumentCommandProcessor(facebook Facebook, messenger Messenger) *Docume
 	}
 }
 
+func NewSenderKeyCommandProcessor(facebook Facebook, messenger Messenger) *SenderKeyCommandProcessor {
+	return &SenderKeyCommandProcessor{
+		BaseCommandProcessor: NewBaseCommandProcessor(facebook, messenger),
+	}
+}
+
+func NewContactCommandProcessor(facebook Facebook, messenger Messenger) *ContactCommandProcessor {
+	return &ContactCommandProcessor{
+		DocumentCommandProcessor: NewDocumentCommandProcessor(facebook, messenger),
+	}
+}
+
+func NewPrivacyCommandProcessor(facebook Facebook, messenger Messenger) *PrivacyCommandProcessor {
+	return &PrivacyCommandProcessor{
+		BaseCommandProcessor: NewBaseCommandProcessor(facebook, messenger),
+	}
+}
+
+func NewRecallCommandProcessor(facebook Facebook, messenger Messenger) *RecallCommandProcessor {
+	return &RecallCommandProcessor{
+		BaseCommandProcessor: NewBaseCommandProcessor(facebook, messenger),
+	}
+}
+
 //
 //  Initialize base creator for CPU factory
 //
@@ -148,4 +166,59 @@ func (cpuHelper) CreateContentProcessorFactory(facebook Facebook, messenger Mess
 func init() {
 	helper := &cpuHelper{}
 	SetContentProcessorHelper(helper)
+	registerContentProcessors()
+}
+
+func registerContentProcessors() {
+	// forward content
+	RegisterContentProcessor(ContentType.FORWARD, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewGroupForwardContentProcessor(facebook, messenger)
+	})
+	// array content
+	RegisterContentProcessor(ContentType.ARRAY, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewArrayContentProcessor(facebook, messenger)
+	})
+	// application customized
+	RegisterContentProcessor(ContentType.CUSTOMIZED, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewCustomizedContentProcessor(facebook, messenger)
+	})
+	RegisterContentProcessor(ContentType.APPLICATION, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewCustomizedContentProcessor(facebook, messenger)
+	})
+	// default commands
+	RegisterContentProcessor(ContentType.COMMAND, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewBaseCommandProcessor(facebook, messenger)
+	})
+	// default contents
+	RegisterContentProcessor(ContentType.ANY, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		// must return a default processor for unknown type
+		return NewBaseContentProcessor(facebook, messenger)
+	})
+	// meta command
+	RegisterCommandProcessor(META, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewMetaCommandProcessor(facebook, messenger)
+	})
+	// documents command
+	RegisterCommandProcessor(DOCUMENTS, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewDocumentCommandProcessor(facebook, messenger)
+	})
+	// sender key command
+	RegisterCommandProcessor(SENDER_KEY, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewSenderKeyCommandProcessor(facebook, messenger)
+	})
+	// contact command
+	RegisterCommandProcessor(CONTACT, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewContactCommandProcessor(facebook, messenger)
+	})
+	// privacy commands
+	RegisterCommandProcessor(BLOCK, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewPrivacyCommandProcessor(facebook, messenger)
+	})
+	RegisterCommandProcessor(MUTE, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewPrivacyCommandProcessor(facebook, messenger)
+	})
+	// recall command
+	RegisterCommandProcessor(RECALL, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewRecallCommandProcessor(facebook, messenger)
+	})
 }
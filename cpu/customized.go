@@ -0,0 +1,297 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	"sync"
+
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+// CustomizedContent is the content for application customized actions
+//
+//	data format: {
+//	    "type" : i2s(0xCC),
+//	    "sn"   : 123,
+//
+//	    "app"  : "{APP_ID}",  // application (e.g.: "chat.dim.sechat")
+//	    "mod"  : "{MODULE}",  // module name (e.g.: "drift_bottle")
+//	    "act"  : "{ACTION}",  // action name (e.g.: "throw")
+//	    "extra": info         // action parameters
+//	}
+type CustomizedContent interface {
+	Content
+
+	// Application returns the app ID
+	Application() string
+
+	// Module returns the module name
+	Module() string
+
+	// Action returns the action name
+	Action() string
+}
+
+type BaseCustomizedContent struct {
+	//CustomizedContent
+	*BaseContent
+}
+
+func NewCustomizedContent(msgType MessageType, app, mod, act string) *BaseCustomizedContent {
+	if msgType == "" {
+		msgType = ContentType.CUSTOMIZED
+	}
+	content := &BaseCustomizedContent{
+		BaseContent: NewBaseContent(nil, msgType),
+	}
+	content.Set("app", app)
+	content.Set("mod", mod)
+	content.Set("act", act)
+	return content
+}
+
+func NewCustomizedContentWithMap(dict StringKeyMap) *BaseCustomizedContent {
+	return &BaseCustomizedContent{
+		BaseContent: NewBaseContent(dict, ""),
+	}
+}
+
+// Override
+func (content *BaseCustomizedContent) Application() string {
+	return content.GetString("app", "")
+}
+
+// Override
+func (content *BaseCustomizedContent) Module() string {
+	return content.GetString("mod", "")
+}
+
+// Override
+func (content *BaseCustomizedContent) Action() string {
+	return content.GetString("act", "")
+}
+
+/**
+ *  Handler for customized content
+ */
+
+type CustomizedContentHandler interface {
+
+	// HandleContent processes the action of customized content
+	//
+	// Parameters:
+	//   - content   - Customized content
+	//   - rMsg      - Network message carrying the content
+	//   - messenger - Message transceiver
+	// Returns: responses
+	HandleContent(content CustomizedContent, rMsg ReliableMessage, messenger Messenger) []Content
+}
+
+// BaseCustomizedHandler is the default handler, responds receipt for unsupported actions
+type BaseCustomizedHandler struct {
+	//CustomizedContentHandler
+}
+
+// Override
+func (handler BaseCustomizedHandler) HandleContent(content CustomizedContent, rMsg ReliableMessage, _ Messenger) []Content {
+	return handler.RespondReceipt("Content not support.", rMsg.Envelope(), content, StringKeyMap{
+		"template": "Customized content (app: ${app}, mod: ${mod}, act: ${act}) not support yet!",
+		"replacements": StringKeyMap{
+			"app": content.Application(),
+			"mod": content.Module(),
+			"act": content.Action(),
+		},
+	})
+}
+
+// protected
+func (handler BaseCustomizedHandler) RespondReceipt(text string, head Envelope, body Content, extra StringKeyMap) []Content {
+	res := createReceipt(text, head, body, extra)
+	return []Content{res}
+}
+
+// deniedCustomizedHandler responds receipt for senders not in allow-list
+type deniedCustomizedHandler struct {
+	BaseCustomizedHandler
+}
+
+// Override
+func (handler deniedCustomizedHandler) HandleContent(content CustomizedContent, rMsg ReliableMessage, _ Messenger) []Content {
+	return handler.RespondReceipt("Permission denied.", rMsg.Envelope(), content, StringKeyMap{
+		"template": "Sender (${sender}) not allowed to use app: ${app}.",
+		"replacements": StringKeyMap{
+			"sender": rMsg.Sender().String(),
+			"app":    content.Application(),
+		},
+	})
+}
+
+/**
+ *  Filter for customized content
+ */
+
+type CustomizedContentFilter interface {
+
+	// FilterContent selects the handler for the customized content
+	//
+	// Returns: handler (never nil)
+	FilterContent(content CustomizedContent, rMsg ReliableMessage) CustomizedContentHandler
+}
+
+// GeneralCustomizedFilter dispatches customized content by 'app', 'mod' & 'act'
+//
+// Handler lookup order: "app:mod:act" -> "app:mod" -> default handler;
+// if an allow-list is set for the app, only senders in the list can use it.
+type GeneralCustomizedFilter struct {
+	//CustomizedContentFilter
+
+	mutex sync.RWMutex
+
+	DefaultHandler CustomizedContentHandler
+
+	handlers   map[string]CustomizedContentHandler
+	allowLists map[string][]ID
+}
+
+func NewGeneralCustomizedFilter() *GeneralCustomizedFilter {
+	return &GeneralCustomizedFilter{
+		DefaultHandler: &BaseCustomizedHandler{},
+		handlers:       make(map[string]CustomizedContentHandler, 8),
+		allowLists:     make(map[string][]ID, 8),
+	}
+}
+
+// SetContentHandler registers handler for all actions in the module
+func (filter *GeneralCustomizedFilter) SetContentHandler(app, mod string, handler CustomizedContentHandler) {
+	filter.setHandler(app+":"+mod, handler)
+}
+
+// SetActionHandler registers handler for the action in the module
+func (filter *GeneralCustomizedFilter) SetActionHandler(app, mod, act string, handler CustomizedContentHandler) {
+	filter.setHandler(app+":"+mod+":"+act, handler)
+}
+
+func (filter *GeneralCustomizedFilter) setHandler(key string, handler CustomizedContentHandler) {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	if handler == nil {
+		delete(filter.handlers, key)
+	} else {
+		filter.handlers[key] = handler
+	}
+}
+
+// SetAllowList restricts the senders who can use the app (nil to allow everyone)
+func (filter *GeneralCustomizedFilter) SetAllowList(app string, senders []ID) {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	if senders == nil {
+		delete(filter.allowLists, app)
+	} else {
+		filter.allowLists[app] = senders
+	}
+}
+
+// IsAllowed checks the allow-list of the app
+func (filter *GeneralCustomizedFilter) IsAllowed(app string, sender ID) bool {
+	filter.mutex.RLock()
+	defer filter.mutex.RUnlock()
+	senders, exists := filter.allowLists[app]
+	if !exists {
+		return true
+	}
+	return containsID(senders, sender)
+}
+
+// protected
+func (filter *GeneralCustomizedFilter) GetContentHandler(app, mod, act string) CustomizedContentHandler {
+	filter.mutex.RLock()
+	defer filter.mutex.RUnlock()
+	if handler := filter.handlers[app+":"+mod+":"+act]; handler != nil {
+		return handler
+	}
+	return filter.handlers[app+":"+mod]
+}
+
+// Override
+func (filter *GeneralCustomizedFilter) FilterContent(content CustomizedContent, rMsg ReliableMessage) CustomizedContentHandler {
+	app := content.Application()
+	if !filter.IsAllowed(app, rMsg.Sender()) {
+		return &deniedCustomizedHandler{}
+	}
+	handler := filter.GetContentHandler(app, content.Module(), content.Action())
+	if handler == nil {
+		return filter.DefaultHandler
+	}
+	return handler
+}
+
+var sharedCustomizedContentFilter CustomizedContentFilter = NewGeneralCustomizedFilter()
+
+func SetCustomizedContentFilter(filter CustomizedContentFilter) {
+	sharedCustomizedContentFilter = filter
+}
+
+func GetCustomizedContentFilter() CustomizedContentFilter {
+	return sharedCustomizedContentFilter
+}
+
+/**
+ *  CPU for CustomizedContent
+ */
+
+type CustomizedContentProcessor struct {
+	*BaseContentProcessor
+}
+
+// Override
+func (cpu *CustomizedContentProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
+	customized, ok := content.(CustomizedContent)
+	if !ok {
+		// content factory for customized content not registered
+		customized = NewCustomizedContentWithMap(content.Map())
+	}
+	// get handler for 'app' & 'mod'
+	filter := GetCustomizedContentFilter()
+	handler := filter.FilterContent(customized, rMsg)
+	if handler == nil {
+		//panic("should not happen")
+		return nil
+	}
+	// handle the action
+	messenger := cpu.Messenger
+	return handler.HandleContent(customized, rMsg, messenger)
+}
@@ -96,6 +96,18 @@ func (cpu *DocumentCommandProcessor) getDocuments(did ID, envelope Envelope, con
 			})
 		}
 	}
+	// check access policy
+	if policy := GetDocumentAccessPolicy(); policy != nil {
+		documents = policy.FilterDocuments(did, documents, envelope.Sender(), cpu.Facebook)
+		if len(documents) == 0 {
+			return cpu.RespondReceipt("Document not found.", envelope, content, StringKeyMap{
+				"template": "Document not found: ${did}",
+				"replacements": StringKeyMap{
+					"did": did.String(),
+				},
+			})
+		}
+	}
 	// document got
 	return cpu.respondDocuments(did, documents, envelope.Sender())
 }
@@ -0,0 +1,233 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	"sync"
+
+	. "github.com/dimchat/mkm-go/crypto"
+	. "github.com/dimchat/mkm-go/ext"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+type DocumentAccess int
+
+const (
+	DocumentPublic       DocumentAccess = iota // visible to everyone
+	DocumentContactsOnly                       // visible to the owner's contacts
+	DocumentCustom                             // decided by the predicate
+)
+
+// DocumentAccessRule decides who can see the documents of the owner
+//
+//	When the requester is not permitted:
+//	    if the owner has published a public variant, it will be responded;
+//	    else the document will be withheld.
+type DocumentAccessRule struct {
+	Access DocumentAccess
+
+	// Predicate is used for DocumentCustom
+	Predicate func(owner ID, requester ID, doc Document) bool
+}
+
+// PublicDocumentSource supplies the public variants of documents
+//
+//	A public variant is a separate document with the public fields only,
+//	signed by the owner (see CreatePublicDocument), so the receiver can verify it.
+type PublicDocumentSource interface {
+
+	// GetPublicDocument returns the public variant of the document (nil if not published)
+	GetPublicDocument(owner ID, doc Document) Document
+}
+
+// DocumentAccessPolicy filters documents before responding
+type DocumentAccessPolicy interface {
+
+	// FilterDocuments returns the documents (or redacted copies) visible to the requester
+	//
+	// Parameters:
+	//   - owner: document ID
+	//   - documents: all documents of the owner
+	//   - requester: sender of the query
+	//   - facebook: for checking contacts
+	//
+	// Returns: visible documents (nil to respond nothing)
+	FilterDocuments(owner ID, documents []Document, requester ID, facebook Facebook) []Document
+}
+
+var sharedDocumentAccessPolicy DocumentAccessPolicy = nil
+
+// SetDocumentAccessPolicy sets the policy (nil means all documents are public)
+func SetDocumentAccessPolicy(policy DocumentAccessPolicy) {
+	sharedDocumentAccessPolicy = policy
+}
+
+func GetDocumentAccessPolicy() DocumentAccessPolicy {
+	return sharedDocumentAccessPolicy
+}
+
+/**
+ *  General Document Access Policy
+ *  ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+ *
+ *  Rules for owner & document type:
+ *      1. rule for the owner & the document type;
+ *      2. rule for the owner (any type);
+ *      3. rule for the document type (any owner);
+ *      4. default rule.
+ */
+type GeneralDocumentAccessPolicy struct {
+	mutex sync.RWMutex
+	rules map[string]*DocumentAccessRule
+
+	DefaultRule *DocumentAccessRule
+
+	// PublicDocuments supplies the public variants for the requesters not permitted
+	// (nil to withhold the documents)
+	PublicDocuments PublicDocumentSource
+}
+
+func NewGeneralDocumentAccessPolicy() *GeneralDocumentAccessPolicy {
+	return &GeneralDocumentAccessPolicy{
+		rules: make(map[string]*DocumentAccessRule, 16),
+		DefaultRule: &DocumentAccessRule{
+			Access: DocumentPublic,
+		},
+	}
+}
+
+func documentAccessKey(owner ID, docType DocumentType) string {
+	if owner == nil {
+		return "*|" + docType
+	}
+	return owner.String() + "|" + docType
+}
+
+// SetRule sets rule for owner (nil for all) & document type ("" for all types),
+// nil rule to remove
+func (policy *GeneralDocumentAccessPolicy) SetRule(rule *DocumentAccessRule, owner ID, docType DocumentType) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	key := documentAccessKey(owner, docType)
+	if rule == nil {
+		delete(policy.rules, key)
+	} else {
+		policy.rules[key] = rule
+	}
+}
+
+func (policy *GeneralDocumentAccessPolicy) GetRule(owner ID, docType DocumentType) *DocumentAccessRule {
+	policy.mutex.RLock()
+	defer policy.mutex.RUnlock()
+	keys := []string{
+		documentAccessKey(owner, docType),
+		documentAccessKey(owner, ""),
+		documentAccessKey(nil, docType),
+	}
+	for _, key := range keys {
+		if rule := policy.rules[key]; rule != nil {
+			return rule
+		}
+	}
+	return policy.DefaultRule
+}
+
+// Override
+func (policy *GeneralDocumentAccessPolicy) FilterDocuments(owner ID, documents []Document, requester ID, facebook Facebook) []Document {
+	helper := GetGeneralAccountHelper()
+	visible := make([]Document, 0, len(documents))
+	var docType DocumentType
+	var rule *DocumentAccessRule
+	for _, doc := range documents {
+		docType = helper.GetDocumentType(doc.Map(), "")
+		rule = policy.GetRule(owner, docType)
+		if rule == nil || policy.isPermitted(rule, owner, requester, doc, facebook) {
+			visible = append(visible, doc)
+		} else if public := policy.getPublicDocument(owner, doc); public != nil {
+			visible = append(visible, public)
+		}
+	}
+	return visible
+}
+
+// protected
+func (policy *GeneralDocumentAccessPolicy) getPublicDocument(owner ID, doc Document) Document {
+	source := policy.PublicDocuments
+	if source == nil {
+		return nil
+	}
+	return source.GetPublicDocument(owner, doc)
+}
+
+// protected
+func (policy *GeneralDocumentAccessPolicy) isPermitted(rule *DocumentAccessRule, owner, requester ID, doc Document, facebook Facebook) bool {
+	if owner.Equal(requester) {
+		return true
+	}
+	switch rule.Access {
+	case DocumentPublic:
+		return true
+	case DocumentContactsOnly:
+		return facebook != nil && containsID(facebook.GetContacts(owner), requester)
+	case DocumentCustom:
+		return rule.Predicate != nil && rule.Predicate(owner, requester, doc)
+	}
+	return false
+}
+
+// CreatePublicDocument creates the public variant with the public fields only,
+// signed by the owner's private key
+func CreatePublicDocument(doc Document, publicFields []string, privateKey SignKey) Document {
+	properties := doc.Properties()
+	if properties == nil {
+		//panic("document error")
+		return nil
+	}
+	dict := doc.CopyMap(false)
+	delete(dict, "data")
+	delete(dict, "signature")
+	public := ParseDocument(dict)
+	if public == nil {
+		//panic("failed to create document")
+		return nil
+	}
+	for _, name := range publicFields {
+		if value, exists := properties[name]; exists {
+			public.SetProperty(name, value)
+		}
+	}
+	if public.Sign(privateKey) == nil {
+		//panic("failed to sign document")
+		return nil
+	}
+	return public
+}
@@ -0,0 +1,107 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// MaxNestingDepth limits the nesting of Forward/Array contents
+var MaxNestingDepth = 8
+
+/**
+ *  Processing state for nested contents (local only)
+ *
+ *      '_nesting_depth' - depth of the nested message/content, set by parent processor;
+ *      '_nesting_seen'  - signatures of messages processed in the same tree,
+ *                         shared by the root message and all nested messages.
+ */
+const (
+	nestingDepthKey = "_nesting_depth"
+	nestingSeenKey  = "_nesting_seen"
+)
+
+func nestingDepthOf(info Mapper) int {
+	depth := info.GetInt(nestingDepthKey, 0)
+	if depth < 0 {
+		// forged?
+		return 0
+	}
+	return depth
+}
+
+// nestingDepth returns the depth of content in processing tree
+func nestingDepth(content Content, rMsg ReliableMessage) int {
+	return nestingDepthOf(rMsg) + nestingDepthOf(content)
+}
+
+// nestingSeen returns the signatures set shared by the processing tree
+//
+// Returns: seen set, and whether it's created for the root message
+func nestingSeen(rMsg ReliableMessage) (map[string]bool, bool) {
+	if seen, ok := rMsg.Get(nestingSeenKey).(map[string]bool); ok {
+		return seen, false
+	}
+	seen := make(map[string]bool)
+	rMsg.Set(nestingSeenKey, seen)
+	return seen, true
+}
+
+func nestingMessageKey(rMsg ReliableMessage) string {
+	signature := rMsg.GetString("signature", "")
+	if signature == "" {
+		return rMsg.GetString("data", "")
+	}
+	return signature
+}
+
+// protected
+func (cpu *BaseContentProcessor) respondNestingTooDeep(depth int, content Content, rMsg ReliableMessage) []Content {
+	return cpu.RespondReceipt("Nesting too deep.", rMsg.Envelope(), content, StringKeyMap{
+		"template": "Content (type: ${type}) nesting depth ${depth} exceeds limit ${limit}.",
+		"replacements": StringKeyMap{
+			"type":  content.Type(),
+			"depth": depth,
+			"limit": MaxNestingDepth,
+		},
+	})
+}
+
+// protected
+func (cpu *BaseContentProcessor) respondNestingLoop(content Content, rMsg ReliableMessage) []Content {
+	return cpu.RespondReceipt("Duplicated nested message.", rMsg.Envelope(), content, StringKeyMap{
+		"template": "Content (type: ${type}) contains a message appeared twice.",
+		"replacements": StringKeyMap{
+			"type": content.Type(),
+		},
+	})
+}
@@ -0,0 +1,141 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+// BLOCK & MUTE are the commands for syncing privacy lists between the user's own terminals
+//
+//	data format: {
+//	    "type" : i2s(0x88),
+//	    "sn"   : 123,
+//
+//	    "command" : "block",  // or "mute"
+//	    "list"    : [...]     // ID list (omitted for query)
+//	}
+//
+// NOTICE: these commands must be sent to the user self
+const (
+	BLOCK = "block"
+	MUTE  = "mute"
+)
+
+// NewPrivacyCommand creates block/mute command, nil list for query
+func NewPrivacyCommand(cmd string, list []ID) Command {
+	content := NewBaseCommand(nil, "", cmd)
+	if list != nil {
+		content.Set("list", IDRevert(list))
+	}
+	return content
+}
+
+func NewBlockCommand(list []ID) Command {
+	return NewPrivacyCommand(BLOCK, list)
+}
+
+func NewMuteCommand(list []ID) Command {
+	return NewPrivacyCommand(MUTE, list)
+}
+
+/**
+ *  CPU for BlockCommand & MuteCommand
+ */
+
+type PrivacyCommandProcessor struct {
+	*BaseCommandProcessor
+}
+
+// Override
+func (cpu *PrivacyCommandProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
+	command, ok := content.(Command)
+	if !ok {
+		//panic("privacy command error")
+		return nil
+	}
+	dataSource := GetPrivacyDataSource()
+	if dataSource == nil {
+		//panic("privacy data source not set")
+		return nil
+	}
+	// 1. privacy lists can be synced between the user's own terminals only
+	sender := rMsg.Sender()
+	user := cpu.Facebook.SelectUser(rMsg.Receiver())
+	if user == nil || rMsg.Receiver().IsBroadcast() || !user.Equal(sender) {
+		return cpu.RespondReceipt("Permission denied.", rMsg.Envelope(), content, StringKeyMap{
+			"template": "Privacy list can only be synced by the owner: ${did}.",
+			"replacements": StringKeyMap{
+				"did": sender.String(),
+			},
+		})
+	}
+	cmd := command.CMD()
+	value := command.Get("list")
+	if value == nil {
+		// 2. query privacy list
+		var list []ID
+		if cmd == BLOCK {
+			list = dataSource.GetBlockList(user)
+		} else {
+			list = dataSource.GetMuteList(user)
+		}
+		if list == nil {
+			list = []ID{}
+		}
+		return []Content{
+			NewPrivacyCommand(cmd, list),
+		}
+	}
+	// 3. save privacy list
+	list := IDConvert(value)
+	var saved bool
+	if cmd == BLOCK {
+		saved = dataSource.SaveBlockList(list, user)
+	} else {
+		saved = dataSource.SaveMuteList(list, user)
+	}
+	if !saved {
+		return cpu.RespondReceipt("Privacy list not saved.", rMsg.Envelope(), content, StringKeyMap{
+			"template": "Failed to save ${command} list.",
+			"replacements": StringKeyMap{
+				"command": cmd,
+			},
+		})
+	}
+	// no need to respond
+	return nil
+}
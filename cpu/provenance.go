@@ -0,0 +1,113 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// ForwardProvenance records one hop of a forwarded message
+//
+// Both signatures are verified:
+// the forwarder signed the outer message, and the sender signed the inner message.
+type ForwardProvenance struct {
+
+	// Forwarder is the sender of the outer message (ForwardContent)
+	Forwarder ID
+
+	// ForwardTime is the time of the outer message
+	ForwardTime Time
+
+	// Sender is the sender of the inner (forwarded) message
+	Sender ID
+
+	// SendTime is the time of the inner message
+	SendTime Time
+}
+
+/**
+ *  Provenance chain (local only)
+ *
+ *      '_provenance' - []ForwardProvenance, from the outermost forwarder to the original sender,
+ *                      it is set on the forwarded message and its verified SecureMessage
+ *                      after the signature verified, then copied into the decrypted
+ *                      InstantMessage, so content processors (with the forwarded message
+ *                      as 'rMsg'), the message store and UIs can show "forwarded from X by Y".
+ *
+ *  NOTICE: values decoded from network data are not of type []ForwardProvenance,
+ *          so they will be ignored.
+ */
+const provenanceKey = "_provenance"
+
+// GetForwardProvenance returns the verified provenance chain of a message
+//
+// Parameters:
+//   - msg - ReliableMessage/InstantMessage forwarded to me
+//
+// Returns: hops from the outermost forwarder to the original sender, nil if not forwarded
+func GetForwardProvenance(msg Mapper) []ForwardProvenance {
+	chain, _ := msg.Get(provenanceKey).([]ForwardProvenance)
+	return chain
+}
+
+// GetOriginalSender returns the signer of the forwarded message
+func GetOriginalSender(msg Mapper) ID {
+	chain := GetForwardProvenance(msg)
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain[len(chain)-1].Sender
+}
+
+// appendProvenance builds the chain for the inner message
+func appendProvenance(rMsg, item ReliableMessage) []ForwardProvenance {
+	parent := GetForwardProvenance(rMsg)
+	chain := make([]ForwardProvenance, 0, len(parent)+1)
+	chain = append(chain, parent...)
+	return append(chain, ForwardProvenance{
+		Forwarder:   rMsg.Sender(),
+		ForwardTime: rMsg.Time(),
+		Sender:      item.Sender(),
+		SendTime:    item.Time(),
+	})
+}
+
+// protected
+func (cpu *BaseContentProcessor) respondNotVerified(item ReliableMessage, content Content, rMsg ReliableMessage) []Content {
+	return cpu.RespondReceipt("Forwarded message not verified.", rMsg.Envelope(), content, StringKeyMap{
+		"template": "Signature of forwarded message from ${sender} not verified.",
+		"replacements": StringKeyMap{
+			"sender": item.Sender().String(),
+		},
+	})
+}
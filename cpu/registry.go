@@ -0,0 +1,140 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	"sort"
+	"sync"
+
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/sdk-go/dkd"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+// ContentProcessorConstructor creates a processor for the messenger
+type ContentProcessorConstructor = func(facebook Facebook, messenger Messenger) ContentProcessor
+
+// ContentProcessorRegistry keeps constructors of processors by content type and command name
+//
+// Used by BaseContentProcessorCreator, the built-in processors are registered in init(),
+// applications can register new processors or override the built-in ones.
+type ContentProcessorRegistry struct {
+	mutex    sync.RWMutex
+	contents map[MessageType]ContentProcessorConstructor
+	commands map[string]ContentProcessorConstructor
+}
+
+func NewContentProcessorRegistry() *ContentProcessorRegistry {
+	return &ContentProcessorRegistry{
+		contents: make(map[MessageType]ContentProcessorConstructor),
+		commands: make(map[string]ContentProcessorConstructor),
+	}
+}
+
+// SetContentConstructor registers (or overrides) constructor for content type, nil to remove
+func (registry *ContentProcessorRegistry) SetContentConstructor(msgType MessageType, fn ContentProcessorConstructor) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if fn == nil {
+		delete(registry.contents, msgType)
+	} else {
+		registry.contents[msgType] = fn
+	}
+}
+
+// SetCommandConstructor registers (or overrides) constructor for command name, nil to remove
+func (registry *ContentProcessorRegistry) SetCommandConstructor(cmdName string, fn ContentProcessorConstructor) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if fn == nil {
+		delete(registry.commands, cmdName)
+	} else {
+		registry.commands[cmdName] = fn
+	}
+}
+
+func (registry *ContentProcessorRegistry) GetContentConstructor(msgType MessageType) ContentProcessorConstructor {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	return registry.contents[msgType]
+}
+
+func (registry *ContentProcessorRegistry) GetCommandConstructor(cmdName string) ContentProcessorConstructor {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	return registry.commands[cmdName]
+}
+
+// ContentTypes returns the registered content types
+func (registry *ContentProcessorRegistry) ContentTypes() []MessageType {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	keys := make([]MessageType, 0, len(registry.contents))
+	for name := range registry.contents {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CommandNames returns the registered command names
+func (registry *ContentProcessorRegistry) CommandNames() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	keys := make([]string, 0, len(registry.commands))
+	for name := range registry.commands {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var sharedContentProcessorRegistry = NewContentProcessorRegistry()
+
+func GetContentProcessorRegistry() *ContentProcessorRegistry {
+	return sharedContentProcessorRegistry
+}
+
+// RegisterContentProcessor registers (or overrides) processor constructor for content type
+//
+// Usage:
+//
+//	RegisterContentProcessor(ContentType.TEXT, func(facebook Facebook, messenger Messenger) ContentProcessor {
+//	    return NewTextContentProcessor(facebook, messenger)
+//	})
+func RegisterContentProcessor(msgType MessageType, fn ContentProcessorConstructor) {
+	sharedContentProcessorRegistry.SetContentConstructor(msgType, fn)
+}
+
+// RegisterCommandProcessor registers (or overrides) processor constructor for command name
+func RegisterCommandProcessor(cmdName string, fn ContentProcessorConstructor) {
+	sharedContentProcessorRegistry.SetCommandConstructor(cmdName, fn)
+}
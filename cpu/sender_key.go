@@ -0,0 +1,111 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/format"
+	. "github.com/dimchat/mkm-go/types"
+	. "github.com/dimchat/sdk-go/msg"
+)
+
+/**
+ *  CPU for SenderKeyCommand
+ */
+
+type SenderKeyCommandProcessor struct {
+	*BaseCommandProcessor
+}
+
+// Override
+func (cpu *SenderKeyCommandProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
+	command, ok := content.(Command)
+	if !ok {
+		//panic("sender key command error")
+		return nil
+	}
+	delegate := GetSenderKeyDelegate()
+	if delegate == nil {
+		//panic("sender key delegate not set")
+		return nil
+	}
+	group := command.Group()
+	chainKey := Base64Decode(ConvertString(command.Get("key"), ""))
+	index := ConvertInt64(command.Get("index"), -1)
+	generation := ConvertInt64(command.Get("generation"), 0)
+	if group == nil || len(chainKey) == 0 || index < 0 || index > MaxSenderKeyIteration || generation < 0 {
+		// error
+		return cpu.RespondReceipt("Sender key command error.", rMsg.Envelope(), content, nil)
+	}
+	sender := rMsg.Sender()
+	// 1. chain key must be sent pairwise
+	if rMsg.Receiver().IsGroup() {
+		return cpu.RespondReceipt("Sender key exposed.", rMsg.Envelope(), content, StringKeyMap{
+			"template": "Sender key must be sent to member directly: ${gid}.",
+			"replacements": StringKeyMap{
+				"gid": group.String(),
+			},
+		})
+	}
+	// 2. sender must be a member of the group
+	if !containsID(cpu.Facebook.GetMembers(group), sender) {
+		return cpu.RespondReceipt("Permission denied.", rMsg.Envelope(), content, StringKeyMap{
+			"template": "Not a member of group: ${gid}.",
+			"replacements": StringKeyMap{
+				"gid": group.String(),
+			},
+		})
+	}
+	// 3. reject replayed (older) chain
+	if old := delegate.GetSenderKey(sender, group); old != nil && cpu.isExpired(old, uint32(generation), uint32(index)) {
+		return cpu.RespondReceipt("Sender key expired.", rMsg.Envelope(), content, StringKeyMap{
+			"template": "Sender key expired: ${gid}.",
+			"replacements": StringKeyMap{
+				"gid": group.String(),
+			},
+		})
+	}
+	// 4. save chain key for (sender, group)
+	key := NewSenderKey(chainKey, uint32(index))
+	key.Generation = uint32(generation)
+	delegate.SaveSenderKey(key, sender, group)
+	// no need to respond receipt, the sender key will be distributed to all members
+	return nil
+}
+
+// isExpired checks whether the received chain is older than the stored one
+func (cpu *SenderKeyCommandProcessor) isExpired(old *SenderKey, generation, index uint32) bool {
+	if generation != old.Generation {
+		return generation < old.Generation
+	}
+	return index < old.Iteration
+}
@@ -0,0 +1,161 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package cpu
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+	. "github.com/dimchat/sdk-go/dkd"
+	. "github.com/dimchat/sdk-go/sdk"
+)
+
+// TypedContentHandler handles content of a concrete type
+//
+// Parameters:
+//   - ctx     - Context with facebook & messenger (see FacebookFromContext/MessengerFromContext)
+//   - content - Content of type T
+//   - rMsg    - Network message carrying the content
+//
+// Returns: responses, or error which will be converted to a receipt
+type TypedContentHandler[T Content] func(ctx context.Context, content T, rMsg ReliableMessage) ([]Content, error)
+
+// ContentError is an error with receipt template,
+// returned by handlers to respond a receipt with template & replacements
+type ContentError struct {
+	Text         string
+	Template     string
+	Replacements StringKeyMap
+}
+
+func (err *ContentError) Error() string {
+	return err.Text
+}
+
+type contextKey int
+
+const (
+	facebookContextKey contextKey = iota
+	messengerContextKey
+)
+
+// NewHandlerContext creates context for typed handlers,
+// handlers can be tested with this context and mock facebook/messenger
+func NewHandlerContext(parent context.Context, facebook Facebook, messenger Messenger) context.Context {
+	ctx := context.WithValue(parent, facebookContextKey, facebook)
+	return context.WithValue(ctx, messengerContextKey, messenger)
+}
+
+func FacebookFromContext(ctx context.Context) Facebook {
+	facebook, _ := ctx.Value(facebookContextKey).(Facebook)
+	return facebook
+}
+
+func MessengerFromContext(ctx context.Context) Messenger {
+	messenger, _ := ctx.Value(messengerContextKey).(Messenger)
+	return messenger
+}
+
+/**
+ *  CPU for typed handler
+ */
+
+type TypedContentProcessor[T Content] struct {
+	*BaseContentProcessor
+
+	Handler TypedContentHandler[T]
+}
+
+func NewTypedContentProcessor[T Content](facebook Facebook, messenger Messenger, handler TypedContentHandler[T]) *TypedContentProcessor[T] {
+	return &TypedContentProcessor[T]{
+		BaseContentProcessor: NewBaseContentProcessor(facebook, messenger),
+		Handler:              handler,
+	}
+}
+
+// Override
+func (cpu *TypedContentProcessor[T]) ProcessContent(content Content, rMsg ReliableMessage) []Content {
+	return cpu.ProcessContentWithContext(context.Background(), content, rMsg)
+}
+
+// Override
+func (cpu *TypedContentProcessor[T]) ProcessContentWithContext(ctx context.Context, content Content, rMsg ReliableMessage) []Content {
+	typed, ok := content.(T)
+	if !ok {
+		return cpu.RespondReceipt("Content type mismatch.", rMsg.Envelope(), content, StringKeyMap{
+			"template": "Content (type: ${type}) not match the handler.",
+			"replacements": StringKeyMap{
+				"type": content.Type(),
+			},
+		})
+	}
+	ctx = NewHandlerContext(ctx, cpu.Facebook, cpu.Messenger)
+	responses, err := cpu.Handler(ctx, typed, rMsg)
+	if err != nil {
+		return cpu.respondError(err, content, rMsg)
+	}
+	return responses
+}
+
+// protected
+func (cpu *BaseContentProcessor) respondError(err error, content Content, rMsg ReliableMessage) []Content {
+	var ce *ContentError
+	if errors.As(err, &ce) && ce.Template != "" {
+		return cpu.RespondReceipt(ce.Text, rMsg.Envelope(), content, StringKeyMap{
+			"template":     ce.Template,
+			"replacements": ce.Replacements,
+		})
+	}
+	return cpu.RespondReceipt(err.Error(), rMsg.Envelope(), content, nil)
+}
+
+// RegisterContentHandler registers (or overrides) typed handler for content type
+//
+// Usage:
+//
+//	RegisterContentHandler(ContentType.TEXT, func(ctx context.Context, content TextContent, rMsg ReliableMessage) ([]Content, error) {
+//	    return nil, nil
+//	})
+func RegisterContentHandler[T Content](msgType MessageType, handler TypedContentHandler[T]) {
+	RegisterContentProcessor(msgType, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewTypedContentProcessor[T](facebook, messenger, handler)
+	})
+}
+
+// RegisterCommandHandler registers (or overrides) typed handler for command name
+func RegisterCommandHandler[T Command](cmdName string, handler TypedContentHandler[T]) {
+	RegisterCommandProcessor(cmdName, func(facebook Facebook, messenger Messenger) ContentProcessor {
+		return NewTypedContentProcessor[T](facebook, messenger, handler)
+	})
+}
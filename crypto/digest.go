@@ -0,0 +1,70 @@
+/* license: https://mit-license.org
+ *
+ *  DIMP : Decentralized Instant Messaging Protocol
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package crypto
+
+import (
+	. "github.com/dimchat/mkm-go/crypto"
+	. "github.com/dimchat/mkm-go/digest"
+	. "github.com/dimchat/mkm-go/format"
+)
+
+// GetKeyDigest calculates a short digest for the symmetric key
+//
+// Lets the receiver check whether the cached key is the one used for encryption,
+// without exposing the key data:
+//
+//	digest = base64(sha256(key.data[-6:]))[-8:]
+//
+// Parameters:
+//   - key - Symmetric key to digest
+//
+// Returns: Key digest string (empty string if key data is too short)
+func GetKeyDigest(key SymmetricKey) string {
+	ted := key.Data()
+	if ted == nil {
+		//panic("key data not found")
+		return ""
+	}
+	data := ted.Bytes()
+	if len(data) < 6 {
+		//panic("key data error")
+		return ""
+	}
+	// get digest for the last 6 bytes of key.data
+	part := data[len(data)-6:]
+	hash := SHA256(part)
+	base64 := Base64Encode(hash)
+	pos := len(base64) - 8
+	if pos < 0 {
+		return base64
+	}
+	return base64[pos:]
+}
@@ -31,6 +31,9 @@
 package cpu
 
 import (
+	"sort"
+	"sync"
+
 	. "github.com/dimchat/core-go/protocol"
 	. "github.com/dimchat/dkd-go/protocol"
 )
@@ -50,9 +53,12 @@ func NewContentProcessorMap(capacity int) ContentProcessorMap {
 // Provides general-purpose content processor management with:
 //  1. Lazy processor creation via ContentProcessorCreator
 //  2. In-memory caching of processors (content/command specific)
+//  3. Runtime registration of processors (overriding the created ones)
 type GeneralContentProcessorFactory struct {
 	//ContentProcessorFactory
 
+	mutex sync.RWMutex
+
 	// creator generates new ContentProcessor instances when not found in cache
 	//
 	// Used for lazy initialization of processors
@@ -99,11 +105,20 @@ func (factory *GeneralContentProcessorFactory) GetContentProcessor(content Conte
 
 // Override
 func (factory *GeneralContentProcessorFactory) GetContentProcessorForType(msgType MessageType) ContentProcessor {
+	factory.mutex.RLock()
 	cpu := factory.contentProcessors[msgType]
+	factory.mutex.RUnlock()
 	if cpu == nil {
 		cpu = factory.creator.CreateContentProcessor(msgType)
 		if cpu != nil {
-			factory.contentProcessors[msgType] = cpu
+			factory.mutex.Lock()
+			if exists := factory.contentProcessors[msgType]; exists != nil {
+				// registered by another goroutine
+				cpu = exists
+			} else {
+				factory.contentProcessors[msgType] = cpu
+			}
+			factory.mutex.Unlock()
 		}
 	}
 	return cpu
@@ -111,12 +126,80 @@ func (factory *GeneralContentProcessorFactory) GetContentProcessorForType(msgTyp
 
 // private
 func (factory *GeneralContentProcessorFactory) GetCommandProcessor(msgType MessageType, cmdName string) ContentProcessor {
+	factory.mutex.RLock()
 	cpu := factory.commandProcessors[cmdName]
+	factory.mutex.RUnlock()
 	if cpu == nil {
 		cpu = factory.creator.CreateCommandProcessor(msgType, cmdName)
 		if cpu != nil {
-			factory.commandProcessors[cmdName] = cpu
+			factory.mutex.Lock()
+			if exists := factory.commandProcessors[cmdName]; exists != nil {
+				// registered by another goroutine
+				cpu = exists
+			} else {
+				factory.commandProcessors[cmdName] = cpu
+			}
+			factory.mutex.Unlock()
 		}
 	}
 	return cpu
 }
+
+//
+//  Runtime Registry
+//
+
+// SetContentProcessor registers a processor for content type,
+// overrides the processor created by creator
+//
+// Parameters:
+//   - msgType - Content type
+//   - cpu     - Content processor (nil to remove, so it will be created by creator again)
+func (factory *GeneralContentProcessorFactory) SetContentProcessor(msgType MessageType, cpu ContentProcessor) {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+	if cpu == nil {
+		delete(factory.contentProcessors, msgType)
+	} else {
+		factory.contentProcessors[msgType] = cpu
+	}
+}
+
+// SetCommandProcessor registers a processor for command name,
+// overrides the processor created by creator
+//
+// Parameters:
+//   - cmdName - Command name
+//   - cpu     - Command processor (nil to remove, so it will be created by creator again)
+func (factory *GeneralContentProcessorFactory) SetCommandProcessor(cmdName string, cpu ContentProcessor) {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+	if cpu == nil {
+		delete(factory.commandProcessors, cmdName)
+	} else {
+		factory.commandProcessors[cmdName] = cpu
+	}
+}
+
+// ContentTypes returns the content types which processors are registered (or created) for
+func (factory *GeneralContentProcessorFactory) ContentTypes() []MessageType {
+	factory.mutex.RLock()
+	defer factory.mutex.RUnlock()
+	return sortedProcessorKeys(factory.contentProcessors)
+}
+
+// CommandNames returns the command names which processors are registered (or created) for
+func (factory *GeneralContentProcessorFactory) CommandNames() []string {
+	factory.mutex.RLock()
+	defer factory.mutex.RUnlock()
+	return sortedProcessorKeys(factory.commandProcessors)
+}
+
+func sortedProcessorKeys(processors ContentProcessorMap) []string {
+	keys := make([]string, 0, len(processors))
+	for name := range processors {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -30,7 +30,11 @@
  */
 package cpu
 
-import . "github.com/dimchat/dkd-go/protocol"
+import (
+	"context"
+
+	. "github.com/dimchat/dkd-go/protocol"
+)
 
 /**
  *  CPU: Content Processing Unit
@@ -53,6 +57,17 @@ type ContentProcessor interface {
 	ProcessContent(content Content, rMsg ReliableMessage) []Content
 }
 
+// ContextContentProcessor is a ContentProcessor which can be cancelled
+//
+// The MessageProcessor calls it with a context carrying the processing deadline,
+// long-running processors should stop when ctx.Done() is closed
+type ContextContentProcessor interface {
+	ContentProcessor
+
+	// ProcessContentWithContext is the same as ProcessContent, but can be cancelled by ctx
+	ProcessContentWithContext(ctx context.Context, content Content, rMsg ReliableMessage) []Content
+}
+
 /**
  *  CPU Creator
  */
@@ -115,7 +115,8 @@ func (packer *PlainMessagePacker) EncryptMessage(iMsg InstantMessage, password S
 	}
 	// encrypt and encode key
 
-	if members == nil {
+	personal := members == nil
+	if personal {
 		// personal message
 		receiver := iMsg.Receiver()
 		members = []ID{
@@ -142,11 +143,19 @@ func (packer *PlainMessagePacker) EncryptMessage(iMsg InstantMessage, password S
 	//  6. Encode message key to String (Base64)
 	//
 	msgKeys := packer.EncodeKeys(bundleMap, iMsg)
-	if len(msgKeys) == 0 {
+	if len(msgKeys) == 0 && len(members) > 0 {
 		// public key for member(s) not found
 		// TODO: suspend this message for waiting member's visa
 		return nil
 	}
+	if !personal {
+		// NOTICE: members who already hold this group key are not listed in 'keys',
+		//         they should get the key from local cache and check it with the digest
+		digest := GetKeyDigest(password)
+		if digest != "" {
+			msgKeys["digest"] = digest
+		}
+	}
 
 	// insert as 'keys'
 	info["keys"] = msgKeys
@@ -175,6 +184,5 @@ func (packer *PlainMessagePacker) EncodeKeys(bundleMap map[ID]EncryptedBundle, i
 			msgKeys[k] = v
 		}
 	}
-	// TODO: put key digest
 	return msgKeys
 }
@@ -65,7 +65,8 @@ type InstantMessagePacker interface {
 	// Parameters:
 	//   - iMsg     - Plaintext instant message to encrypt
 	//   - password - Symmetric key used to encrypt the message content
-	//   - members  - List of group member IDs (nil for personal messages)
+	//   - members  - List of group member IDs (nil for personal messages),
+	//                members who already hold the group key can be excluded
 	// Returns: Encrypted SecureMessage (nil if receiver's visa/encryption key is not found)
 	EncryptMessage(iMsg InstantMessage, password SymmetricKey, members []ID) SecureMessage
 }
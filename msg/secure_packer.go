@@ -109,9 +109,10 @@ func (packer *EncryptedMessagePacker) DecryptMessage(sMsg SecureMessage, receive
 	if password == nil {
 		// A: key data is empty, and cipher key not found from local storage;
 		// B: key data error.
-		panic(fmt.Sprintf("failed to get message key: %d byte(s), %s => %s",
-			len(pwd), sMsg.Sender().String(), receiver.String()))
+		//panic(fmt.Sprintf("failed to get message key: %d byte(s), %s => %s",
+		//	len(pwd), sMsg.Sender().String(), receiver.String()))
 		// TODO: ask the sender to send again (with new message key)
+		return nil
 	}
 
 	//
@@ -0,0 +1,312 @@
+/* license: https://mit-license.org
+ *
+ *  DIMP : Decentralized Instant Messaging Protocol
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package dkd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/mkm-go/crypto"
+	. "github.com/dimchat/mkm-go/format"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+const (
+	// MaxSenderKeySkip limits how far a receiver will ratchet forward for one message
+	MaxSenderKeySkip = 1000
+
+	// MaxSenderKeyIteration limits how many messages a sender can encrypt with one chain,
+	// after that, a new chain key should be distributed
+	MaxSenderKeyIteration = 0xFFFF
+)
+
+/**
+ *  Sender Key
+ *  ~~~~~~~~~~
+ *
+ *      message_key[i] = HMAC(chain_key[i], 0x01)
+ *      chain_key[i+1] = HMAC(chain_key[i], 0x02)
+ */
+
+// SenderKey is the ratcheting chain state of one member in a group
+//
+// Each member generates its own chain key and distributes it to the other members
+// (through pairwise encrypted commands), then encrypts group messages once
+// with the message key derived from the chain at the current iteration
+type SenderKey struct {
+
+	// ChainKey is the chain key at current iteration
+	ChainKey []byte
+
+	// Iteration is the index of the next message key
+	Iteration uint32
+
+	// Generation increases every time the chain is renewed,
+	// so a replayed (older) chain will be rejected
+	Generation uint32
+
+	// SkippedKeys caches message keys derived for skipped messages (receiver only)
+	//
+	// Key: message key index, Value: message key data
+	SkippedKeys map[uint32][]byte
+
+	// Members who received this chain key (sender only),
+	// the chain must be renewed when the group membership changed
+	Members []ID
+}
+
+func NewSenderKey(chainKey []byte, iteration uint32) *SenderKey {
+	return &SenderKey{
+		ChainKey:    chainKey,
+		Iteration:   iteration,
+		SkippedKeys: make(map[uint32][]byte),
+	}
+}
+
+// GenerateSenderKey creates a new sender key with random chain key
+func GenerateSenderKey() *SenderKey {
+	chainKey := make([]byte, 32)
+	if _, err := rand.Read(chainKey); err != nil {
+		//panic("failed to generate chain key")
+		return nil
+	}
+	return NewSenderKey(chainKey, 0)
+}
+
+// RenewSenderKey creates a new chain with the next generation
+//
+// Parameters:
+//   - old - Current sender key (nil for the first chain)
+//
+// Returns: New sender key (nil on error)
+func RenewSenderKey(old *SenderKey) *SenderKey {
+	key := GenerateSenderKey()
+	if key != nil && old != nil {
+		key.Generation = old.Generation + 1
+	}
+	return key
+}
+
+// Clone copies the chain state, so the state can be updated after the message decrypted
+func (key *SenderKey) Clone() *SenderKey {
+	chainKey := make([]byte, len(key.ChainKey))
+	copy(chainKey, key.ChainKey)
+	skipped := make(map[uint32][]byte, len(key.SkippedKeys))
+	for i, data := range key.SkippedKeys {
+		skipped[i] = data
+	}
+	var members []ID
+	if key.Members != nil {
+		members = make([]ID, len(key.Members))
+		copy(members, key.Members)
+	}
+	return &SenderKey{
+		ChainKey:    chainKey,
+		Iteration:   key.Iteration,
+		Generation:  key.Generation,
+		SkippedKeys: skipped,
+		Members:     members,
+	}
+}
+
+// IsDistributedTo checks whether the chain was distributed to exactly these members
+func (key *SenderKey) IsDistributedTo(members []ID) bool {
+	if len(key.Members) != len(members) {
+		return false
+	}
+	distributed := make(map[string]bool, len(key.Members))
+	for _, item := range key.Members {
+		distributed[item.String()] = true
+	}
+	for _, item := range members {
+		if !distributed[item.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// NextMessageKey derives the message key for sending (sender only)
+//
+// Returns: Index and data of the message key (nil if the chain is exhausted)
+func (key *SenderKey) NextMessageKey() (uint32, []byte) {
+	index := key.Iteration
+	if index >= MaxSenderKeyIteration {
+		// chain exhausted, distribute a new one
+		return index, nil
+	}
+	return index, key.MessageKey(index)
+}
+
+// MessageKey derives the message key with index, ratcheting the chain forward
+//
+// Parameters:
+//   - index - Message key index (from 'message.key_index')
+//
+// Returns: Message key data (nil if the key is expired or too far ahead)
+func (key *SenderKey) MessageKey(index uint32) []byte {
+	if index < key.Iteration {
+		// skipped message (or replayed)
+		data := key.SkippedKeys[index]
+		delete(key.SkippedKeys, index)
+		return data
+	} else if index-key.Iteration > MaxSenderKeySkip {
+		//panic("too many skipped messages")
+		return nil
+	}
+	var data []byte
+	for key.Iteration < index {
+		// cache message keys for the skipped messages
+		data = senderKeyDerive(key.ChainKey, 0x01)
+		key.SkippedKeys[key.Iteration] = data
+		key.ChainKey = senderKeyDerive(key.ChainKey, 0x02)
+		key.Iteration++
+	}
+	for i := range key.SkippedKeys {
+		if index-i > MaxSenderKeySkip {
+			delete(key.SkippedKeys, i)
+		}
+	}
+	data = senderKeyDerive(key.ChainKey, 0x01)
+	key.ChainKey = senderKeyDerive(key.ChainKey, 0x02)
+	key.Iteration++
+	return data
+}
+
+func senderKeyDerive(chainKey []byte, constant byte) []byte {
+	mac := hmac.New(sha256.New, chainKey)
+	mac.Write([]byte{constant})
+	return mac.Sum(nil)
+}
+
+// senderKeyHeaderSize is the length of (generation, index) before the ciphertext,
+// they are inside the signed 'data', so the relay cannot change them
+const senderKeyHeaderSize = 8
+
+func senderKeyPackData(generation, index uint32, ciphertext []byte) []byte {
+	data := make([]byte, senderKeyHeaderSize+len(ciphertext))
+	binary.BigEndian.PutUint32(data[0:4], generation)
+	binary.BigEndian.PutUint32(data[4:8], index)
+	copy(data[senderKeyHeaderSize:], ciphertext)
+	return data
+}
+
+func senderKeyUnpackData(data []byte) (generation, index uint32, ciphertext []byte, ok bool) {
+	if len(data) <= senderKeyHeaderSize {
+		return 0, 0, nil, false
+	}
+	generation = binary.BigEndian.Uint32(data[0:4])
+	index = binary.BigEndian.Uint32(data[4:8])
+	return generation, index, data[senderKeyHeaderSize:], true
+}
+
+// CreateSenderMessageKey builds a symmetric key for message content from the key data
+func CreateSenderMessageKey(data []byte) SymmetricKey {
+	if len(data) == 0 {
+		return nil
+	}
+	info := NewMap()
+	info["algorithm"] = AES
+	info["data"] = Base64Encode(data)
+	return ParseSymmetricKey(info)
+}
+
+// SenderKeyDelegate defines the interface for sender key storage
+//
+// Stores chain states for the local user (as sender) and the other members (as receivers),
+// keys are directional: (sender, group)
+type SenderKeyDelegate interface {
+
+	// GetSenderKey retrieves the chain state of the sender in the group
+	//
+	// Parameters:
+	//   - sender - Group member ID
+	//   - group  - Group ID
+	// Returns: Sender key (nil if not distributed yet)
+	GetSenderKey(sender, group ID) *SenderKey
+
+	// SaveSenderKey stores the chain state of the sender in the group
+	//
+	// Parameters:
+	//   - key    - Sender key (updated after every message)
+	//   - sender - Group member ID
+	//   - group  - Group ID
+	SaveSenderKey(key *SenderKey, sender, group ID)
+}
+
+var sharedSenderKeyDelegate SenderKeyDelegate = nil
+
+func SetSenderKeyDelegate(delegate SenderKeyDelegate) {
+	sharedSenderKeyDelegate = delegate
+}
+
+func GetSenderKeyDelegate() SenderKeyDelegate {
+	return sharedSenderKeyDelegate
+}
+
+// SenderKeyGroup returns the group ID for sender key message
+func SenderKeyGroup(msg StringKeyMap, receiver ID) ID {
+	group := ParseID(msg["group"])
+	if group == nil && receiver.IsGroup() {
+		group = receiver
+	}
+	return group
+}
+
+// SENDER_KEY is the command for distributing sender's chain key to a group member
+//
+//	data format: {
+//	    "type" : i2s(0x88),
+//	    "sn"   : 123,
+//
+//	    "command"    : "sender_key",
+//	    "group"      : "{GROUP_ID}",
+//	    "key"        : "{BASE64_CHAIN_KEY}",
+//	    "index"      : 0,  // iteration of the chain key
+//	    "generation" : 1   // generation of the chain key
+//	}
+//
+// NOTICE: this command must be sent in personal message (pairwise encrypted)
+const SENDER_KEY = "sender_key"
+
+func NewSenderKeyCommand(group ID, key *SenderKey) Command {
+	content := NewBaseCommand(nil, "", SENDER_KEY)
+	content.SetGroup(group)
+	content.Set("key", Base64Encode(key.ChainKey))
+	content.Set("index", key.Iteration)
+	content.Set("generation", key.Generation)
+	return content
+}
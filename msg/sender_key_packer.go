@@ -0,0 +1,301 @@
+/* license: https://mit-license.org
+ *
+ *  DIMP : Decentralized Instant Messaging Protocol
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package dkd
+
+import (
+	. "github.com/dimchat/core-go/format"
+	. "github.com/dimchat/core-go/msg"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/crypto"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+/*
+ *  Sender Key Message
+ *
+ *    +-----------+
+ *    | sender    |
+ *    | receiver  |  group ID (or member ID with 'group')
+ *    | time      |
+ *    |           |
+ *    | key_index |  index of the message key in sender's chain
+ *    | data      |  data = generation + key_index + encrypt(content, message_key[key_index])
+ *    +-----------+
+ *
+ *  Group messages are encrypted only once with the sender key,
+ *  no 'key/keys' needed.
+ *
+ *  NOTICE: 'key_index' outside 'data' is not signed, it's only a hint;
+ *          the real (generation, index) are the first 8 bytes of 'data'.
+ */
+
+// SenderKeyInstantPacker encrypts group messages with the sender's ratcheting chain key
+//
+// If the sender key for this group not found (or exhausted), falls back to the normal packer
+type SenderKeyInstantPacker struct {
+	PlainMessagePacker
+
+	// protected
+	Delegate SenderKeyDelegate
+}
+
+func NewSenderKeyInstantPacker(messenger InstantMessageDelegate, delegate SenderKeyDelegate) *SenderKeyInstantPacker {
+	return &SenderKeyInstantPacker{
+		PlainMessagePacker: PlainMessagePacker{
+			Transformer: messenger,
+		},
+		Delegate: delegate,
+	}
+}
+
+// Override
+func (packer *SenderKeyInstantPacker) EncryptMessage(iMsg InstantMessage, password SymmetricKey, members []ID) SecureMessage {
+	delegate := packer.Delegate
+	if members == nil || delegate == nil || IsBroadcastMessage(iMsg) {
+		// personal message, or sender key not supported
+		return packer.PlainMessagePacker.EncryptMessage(iMsg, password, members)
+	}
+	transformer := packer.Transformer
+	if transformer == nil {
+		//panic("instant message delegate not found")
+		return nil
+	}
+	sender := iMsg.Sender()
+	group := SenderKeyGroup(iMsg.Map(), iMsg.Receiver())
+	if group == nil {
+		return packer.PlainMessagePacker.EncryptMessage(iMsg, password, members)
+	}
+
+	//
+	//  0. derive message key from sender's chain
+	//
+	chain := delegate.GetSenderKey(sender, group)
+	if chain == nil {
+		// sender key not distributed yet
+		return packer.PlainMessagePacker.EncryptMessage(iMsg, password, members)
+	} else if !chain.IsDistributedTo(members) {
+		// membership changed, the removed members still hold this chain,
+		// a new sender key should be distributed
+		return packer.PlainMessagePacker.EncryptMessage(iMsg, password, members)
+	}
+	index, data := chain.NextMessageKey()
+	if data == nil {
+		// chain exhausted, a new sender key should be distributed
+		return packer.PlainMessagePacker.EncryptMessage(iMsg, password, members)
+	}
+	delegate.SaveSenderKey(chain, sender, group)
+	msgKey := CreateSenderMessageKey(data)
+	if msgKey == nil {
+		//panic("failed to create message key")
+		return nil
+	}
+
+	//
+	//  1. Serialize 'message.content' to data (JsON / ProtoBuf / ...)
+	//
+	body := transformer.SerializeContent(iMsg.Content(), msgKey, iMsg)
+	if len(body) == 0 {
+		//panic("fail to serialize content")
+		return nil
+	}
+
+	//
+	//  2. Encrypt content data to 'message.data' with message key
+	//
+	ciphertext := transformer.EncryptContent(body, msgKey, iMsg)
+	if len(ciphertext) == 0 {
+		//panic("fail to encrypt content with key")
+		return nil
+	}
+
+	//
+	//  3. Encode 'message.data' to String (Base64)
+	//
+	encodedData := NewBase64DataWithBytes(senderKeyPackData(chain.Generation, index, ciphertext))
+	if encodedData == nil || encodedData.IsEmpty() {
+		//panic("fail to encode content data")
+		return nil
+	}
+
+	// OK, pack message with key index
+	info := iMsg.CopyMap(false)
+	delete(info, "content")
+	info["data"] = encodedData.Serialize()
+	info["key_index"] = index
+	return ParseSecureMessage(info)
+}
+
+// SenderKeySecurePacker decrypts group messages with the sender's ratcheting chain key
+//
+// Messages without 'key_index' are decrypted by the normal packer
+type SenderKeySecurePacker struct {
+	EncryptedMessagePacker
+
+	// protected
+	Delegate SenderKeyDelegate
+}
+
+func NewSenderKeySecurePacker(messenger SecureMessageDelegate, delegate SenderKeyDelegate) *SenderKeySecurePacker {
+	return &SenderKeySecurePacker{
+		EncryptedMessagePacker: EncryptedMessagePacker{
+			Transformer: messenger,
+		},
+		Delegate: delegate,
+	}
+}
+
+// Override
+func (packer *SenderKeySecurePacker) DecryptMessage(sMsg SecureMessage, receiver ID) InstantMessage {
+	hint, ok := senderKeyIndex(sMsg.Get("key_index"))
+	if !ok {
+		// not a sender key message
+		return packer.EncryptedMessagePacker.DecryptMessage(sMsg, receiver)
+	}
+	transformer := packer.Transformer
+	delegate := packer.Delegate
+	if transformer == nil || delegate == nil {
+		//panic("secure message delegate not found")
+		return nil
+	}
+	sender := sMsg.Sender()
+	group := SenderKeyGroup(sMsg.Map(), sMsg.Receiver())
+	if group == nil {
+		//panic("group ID not found")
+		return nil
+	}
+
+	//
+	//  1. Decode 'message.data' to (generation, index) and encrypted content data
+	//
+	encoded := sMsg.Data()
+	if encoded == nil || encoded.IsEmpty() {
+		//panic("failed to decode message data")
+		return nil
+	}
+	generation, index, ciphertext, ok := senderKeyUnpackData(encoded.Bytes())
+	if !ok || index != hint {
+		//panic("key index not matched")
+		return nil
+	}
+
+	//
+	//  2. Derive message key from a copy of sender's chain,
+	//     the chain state will be saved after the message decrypted
+	//
+	stored := delegate.GetSenderKey(sender, group)
+	if stored == nil {
+		// TODO: ask the sender to distribute the sender key again
+		return nil
+	} else if stored.Generation != generation {
+		// chain renewed, or sender key not received yet
+		return nil
+	}
+	chain := stored.Clone()
+	data := chain.MessageKey(index)
+	if data == nil {
+		// message key expired (or replayed), or too far ahead
+		return nil
+	}
+	msgKey := CreateSenderMessageKey(data)
+	if msgKey == nil {
+		//panic("failed to create message key")
+		return nil
+	}
+
+	//
+	//  3. Decrypt 'message.data' with message key
+	//
+	body := transformer.DecryptContent(ciphertext, msgKey, sMsg)
+	if len(body) == 0 {
+		//panic("failed to decrypt message data")
+		return nil
+	}
+
+	//
+	//  4. Deserialize message content from data (JsON / ProtoBuf / ...)
+	//
+	content := transformer.DeserializeContent(body, msgKey, sMsg)
+	if content == nil {
+		//panic("failed to deserialize content")
+		return nil
+	}
+
+	delegate.SaveSenderKey(chain, sender, group)
+
+	// OK, pack message
+	info := sMsg.CopyMap(false)
+	delete(info, "key_index")
+	delete(info, "data")
+	info["content"] = content.Map()
+	return ParseInstantMessage(info)
+}
+
+func senderKeyIndex(value interface{}) (uint32, bool) {
+	if value == nil {
+		return 0, false
+	}
+	index := ConvertInt64(value, -1)
+	if index < 0 || index > MaxSenderKeyIteration {
+		return 0, false
+	}
+	return uint32(index), true
+}
+
+//
+//  Factory
+//
+
+// SenderKeyPackerFactory creates packers for sender key scheme,
+// usage:
+//
+//	SetSenderKeyDelegate(db)
+//	SetMessagePackerFactory(&SenderKeyPackerFactory{})
+type SenderKeyPackerFactory struct {
+	//MessagePackerFactory
+}
+
+// Override
+func (SenderKeyPackerFactory) CreateInstantMessagePacker(messenger InstantMessageDelegate) InstantMessagePacker {
+	return NewSenderKeyInstantPacker(messenger, GetSenderKeyDelegate())
+}
+
+// Override
+func (SenderKeyPackerFactory) CreateSecureMessagePacker(messenger SecureMessageDelegate) SecureMessagePacker {
+	return NewSenderKeySecurePacker(messenger, GetSenderKeyDelegate())
+}
+
+// Override
+func (SenderKeyPackerFactory) CreateReliableMessagePacker(messenger ReliableMessageDelegate) ReliableMessagePacker {
+	return &NetworkMessagePacker{
+		Transformer: messenger,
+	}
+}
@@ -0,0 +1,174 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"strings"
+
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/mkm"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// GetGroupAssistants returns the group bots from 'assistants' in the latest bulletin
+//
+// Parameters:
+//   - facebook - Entity data source
+//   - gid      - Group ID
+//
+// Returns: Assistant IDs (empty if the group has no assistant)
+func GetGroupAssistants(facebook Facebook, gid ID) []ID {
+	var bulletin Bulletin
+	for _, doc := range facebook.GetDocuments(gid) {
+		item, ok := doc.(Bulletin)
+		if !ok {
+			continue
+		} else if bulletin == nil || Timestamp(item.Time()) > Timestamp(bulletin.Time()) {
+			bulletin = item
+		}
+	}
+	if bulletin == nil {
+		return nil
+	}
+	return IDConvert(bulletin.GetProperty("assistants"))
+}
+
+// RelayGroupMessage packs a group message for the group assistant
+//
+// The group message is encrypted once (with keys for all members) and signed,
+// then wrapped in a ForwardContent and sent to the assistant,
+// which will split it for each member.
+//
+// Parameters:
+//   - iMsg - Instant message sent to a group
+//
+// Returns: Message for the assistant (nil if the group has no assistant)
+func (packer *MessagePacker) RelayGroupMessage(iMsg InstantMessage) ReliableMessage {
+	group := iMsg.Receiver()
+	if !group.IsGroup() {
+		//panic("not a group message: " + group.String())
+		return nil
+	}
+	assistants := GetGroupAssistants(packer.Facebook, group)
+	if len(assistants) == 0 {
+		// no assistant, fan out by the member itself
+		return nil
+	}
+	// 1. pack group message
+	sMsg := packer.EncryptMessage(iMsg)
+	if sMsg == nil {
+		//panic("failed to encrypt group message")
+		return nil
+	}
+	rMsg := packer.SignMessage(sMsg)
+	if rMsg == nil {
+		//panic("failed to sign group message")
+		return nil
+	}
+	// 2. wrap for the assistant
+	bot := assistants[0]
+	envelope := CreateEnvelope(iMsg.Sender(), bot, nil)
+	content := NewForwardMessage(rMsg)
+	content.SetGroup(group)
+	relay := CreateInstantMessage(envelope, content)
+	sMsg = packer.EncryptMessage(relay)
+	if sMsg == nil {
+		// assistant's visa not found
+		return nil
+	}
+	rMsg = packer.SignMessage(sMsg)
+	if rMsg != nil {
+		// record the group message, not the wrapper for the assistant
+		recordOutboundMessage(packer.Messenger, iMsg)
+	}
+	return rMsg
+}
+
+// DeliverGroupMessage packs a group message via the assistant if the group has one,
+// otherwise splits it for each member
+//
+// Parameters:
+//   - iMsg - Instant message sent to a group
+//
+// Returns: Delivery report (nil if the receiver is not a group or group not ready)
+func (packer *MessagePacker) DeliverGroupMessage(iMsg InstantMessage) *GroupDeliveryReport {
+	group := iMsg.Receiver()
+	if !group.IsGroup() {
+		//panic("not a group message: " + group.String())
+		return nil
+	}
+	rMsg := packer.RelayGroupMessage(iMsg)
+	if rMsg != nil {
+		return &GroupDeliveryReport{
+			Group:     group,
+			Assistant: rMsg.Receiver(),
+			Messages:  []ReliableMessage{rMsg},
+		}
+	}
+	return packer.FanOutGroupMessage(iMsg)
+}
+
+// SplitGroupMessage trims a group message for one member (used by the assistant)
+//
+// The message keeps the 'group' field and the member's key only;
+// the signature is still valid because it is only signed on 'data'.
+//
+// Parameters:
+//   - rMsg   - Group message signed by a member
+//   - member - Member ID
+//
+// Returns: Message for the member (nil if the key for the member not found)
+func SplitGroupMessage(rMsg ReliableMessage, member ID) ReliableMessage {
+	group := rMsg.Receiver()
+	info := rMsg.CopyMap(false)
+	info["receiver"] = member.String()
+	info["group"] = group.String()
+	msgKeys := rMsg.EncryptedKeys()
+	if msgKeys != nil {
+		keys := NewMap()
+		identifier := IDConcat(member.Name(), member.Address(), "")
+		for target, value := range msgKeys {
+			// key entries: "ID" or "ID/terminal"
+			if target == identifier || target == "digest" || strings.HasPrefix(target, identifier+"/") {
+				keys[target] = value
+			}
+		}
+		if len(keys) == 0 {
+			// key for this member not found
+			return nil
+		}
+		info["keys"] = keys
+	}
+	return ParseReliableMessage(info)
+}
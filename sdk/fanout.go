@@ -0,0 +1,154 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// GroupDeliveryReport is the result of splitting a group message for each member
+type GroupDeliveryReport struct {
+
+	// Group ID
+	Group ID
+
+	// Assistant is set when the message is relayed by the group assistant
+	Assistant ID
+
+	// Messages contains the packed messages for members in Encrypted
+	// (or the message for the assistant)
+	Messages []ReliableMessage
+
+	// Encrypted contains members whose messages are encrypted and signed successfully
+	Encrypted []ID
+
+	// MissingVisa contains members skipped for visa (encrypt key) not found
+	MissingVisa []ID
+
+	// FailedToEncrypt contains members whose messages failed to split or encrypt
+	// for other reasons (e.g.: message key not found)
+	FailedToEncrypt []ID
+
+	// FailedToSign contains members whose messages are encrypted but failed to sign
+	FailedToSign []ID
+}
+
+// IsComplete checks whether messages for all members are packed
+func (report *GroupDeliveryReport) IsComplete() bool {
+	return len(report.MissingVisa) == 0 && len(report.FailedToEncrypt) == 0 && len(report.FailedToSign) == 0
+}
+
+// FanOutGroupMessage splits a group message into per-member messages
+//
+// Each message will be sent to a member with the 'group' field kept,
+// so the receiver can still take the group message key (sender -> group);
+// the sender itself will be skipped.
+//
+// Parameters:
+//   - iMsg - Instant message sent to a group
+//
+// Returns: Delivery report (nil if the receiver is not a group or group not ready)
+func (packer *MessagePacker) FanOutGroupMessage(iMsg InstantMessage) *GroupDeliveryReport {
+	group := iMsg.Receiver()
+	if !group.IsGroup() {
+		//panic("not a group message: " + group.String())
+		return nil
+	}
+	facebook := packer.Facebook
+	members := facebook.GetMembers(group)
+	if len(members) == 0 {
+		//panic("group not ready")
+		return nil
+	}
+	sender := iMsg.Sender()
+	report := &GroupDeliveryReport{
+		Group: group,
+	}
+	var info StringKeyMap
+	var item InstantMessage
+	var sMsg SecureMessage
+	var rMsg ReliableMessage
+	for _, member := range members {
+		if member.Equal(sender) {
+			// skip myself
+			continue
+		}
+		// 1. split for member
+		info = iMsg.CopyMap(false)
+		info["receiver"] = member.String()
+		info["group"] = group.String()
+		item = ParseInstantMessage(info)
+		if item == nil {
+			//panic("failed to split message")
+			report.FailedToEncrypt = append(report.FailedToEncrypt, member)
+			continue
+		}
+		// 2. encrypt with member's visa key
+		sMsg = packer.EncryptMessage(item)
+		if sMsg == nil {
+			if !hasVisaKey(facebook.GetDocuments(member)) {
+				// public key for encryption not found
+				// TODO: suspend this message for waiting member's visa
+				report.MissingVisa = append(report.MissingVisa, member)
+			} else {
+				// message key not found, or encryption error
+				report.FailedToEncrypt = append(report.FailedToEncrypt, member)
+			}
+			continue
+		}
+		// 3. sign with sender's private key
+		rMsg = packer.SignMessage(sMsg)
+		if rMsg == nil {
+			report.FailedToSign = append(report.FailedToSign, member)
+			continue
+		}
+		report.Messages = append(report.Messages, rMsg)
+		report.Encrypted = append(report.Encrypted, member)
+	}
+	if len(report.Messages) > 0 {
+		// record the group message once, not the copies for members
+		recordOutboundMessage(packer.Messenger, iMsg)
+	}
+	return report
+}
+
+// hasVisaKey checks whether the documents contain a visa with key for encryption
+func hasVisaKey(documents []Document) bool {
+	for _, doc := range documents {
+		if visa, ok := doc.(Visa); ok && visa.PublicKey() != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,439 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// SearchQuery for the stored messages
+//
+//	text format:
+//	    word        - term query
+//	    prefix*     - prefix query
+//	    "a phrase"  - phrase query
+//	all parts must be matched.
+type SearchQuery struct {
+	Text string
+
+	Conversation ID   // nil for all conversations
+	Sender       ID   // nil for all senders
+	Since        Time // nil for no lower bound
+	Until        Time // nil for no upper bound
+
+	Limit int // max count (0 means no limit)
+}
+
+// SearchResult is the matched message, latest first
+type SearchResult struct {
+	Conversation ID
+	Sender       ID
+	SN           SerialNumberType
+	Time         Time
+}
+
+type indexedMessage struct {
+	conversation ID
+	sender       ID
+	sn           SerialNumberType
+	time         int64 // nanoseconds
+	terms        []string
+}
+
+/**
+ *  Message Index
+ *  ~~~~~~~~~~~~~
+ *
+ *  Inverted index over text content & file names:
+ *      term => { message key => positions }
+ */
+type MessageIndex struct {
+	mutex    sync.RWMutex
+	postings map[string]map[string][]int
+	messages map[string]*indexedMessage
+
+	// sorted terms for prefix query
+	terms []string
+	dirty bool
+}
+
+func NewMessageIndex() *MessageIndex {
+	return &MessageIndex{
+		postings: make(map[string]map[string][]int, 1024),
+		messages: make(map[string]*indexedMessage, 1024),
+	}
+}
+
+func messageIndexKey(sn SerialNumberType, conversation ID) string {
+	return conversation.String() + "|" + strconv.FormatUint(sn, 10)
+}
+
+// TokenizeText splits text to lowercase terms,
+// each CJK character is a term
+func TokenizeText(text string) []string {
+	tokens := make([]string, 0, 16)
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			tokens = append(tokens, sb.String())
+			sb.Reset()
+		}
+	}
+	for _, ch := range text {
+		if unicode.In(ch, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			flush()
+			tokens = append(tokens, string(ch))
+		} else if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+			sb.WriteRune(unicode.ToLower(ch))
+		} else {
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// messageTokens returns tokens of text & file name,
+// the gap between fields prevents phrase matching across them
+func messageTokens(content Content) ([]string, []int) {
+	tokens := make([]string, 0, 16)
+	positions := make([]int, 0, 16)
+	pos := 0
+	for _, field := range []string{"text", "filename"} {
+		for _, token := range TokenizeText(content.GetString(field, "")) {
+			tokens = append(tokens, token)
+			positions = append(positions, pos)
+			pos++
+		}
+		pos++
+	}
+	return tokens, positions
+}
+
+// AddMessage indexes the message, the old one with same sn will be replaced
+func (index *MessageIndex) AddMessage(iMsg InstantMessage, conversation ID) {
+	content := iMsg.Content()
+	sn := content.SN()
+	key := messageIndexKey(sn, conversation)
+	tokens, positions := messageTokens(content)
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.removeKey(key)
+	if len(tokens) == 0 {
+		// nothing to search
+		return
+	}
+	item := &indexedMessage{
+		conversation: conversation,
+		sender:       iMsg.Sender(),
+		sn:           sn,
+		terms:        make([]string, 0, len(tokens)),
+	}
+	if when := iMsg.Time(); !TimeIsNil(when) {
+		item.time = TimestampNano(when)
+	}
+	for i, token := range tokens {
+		table := index.postings[token]
+		if table == nil {
+			table = make(map[string][]int, 4)
+			index.postings[token] = table
+			index.dirty = true
+		}
+		if _, exists := table[key]; !exists {
+			item.terms = append(item.terms, token)
+		}
+		table[key] = append(table[key], positions[i])
+	}
+	index.messages[key] = item
+}
+
+// RemoveMessage removes the message from index (deleted or recalled)
+func (index *MessageIndex) RemoveMessage(sn SerialNumberType, conversation ID) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.removeKey(messageIndexKey(sn, conversation))
+}
+
+// RemoveConversation removes all messages of the conversation from index
+func (index *MessageIndex) RemoveConversation(conversation ID) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	prefix := conversation.String() + "|"
+	for key := range index.messages {
+		if strings.HasPrefix(key, prefix) {
+			index.removeKey(key)
+		}
+	}
+}
+
+func (index *MessageIndex) removeKey(key string) {
+	item := index.messages[key]
+	if item == nil {
+		return
+	}
+	for _, term := range item.terms {
+		table := index.postings[term]
+		delete(table, key)
+		if len(table) == 0 {
+			delete(index.postings, term)
+			index.dirty = true
+		}
+	}
+	delete(index.messages, key)
+}
+
+// sortedTerms must be called with write lock
+func (index *MessageIndex) sortedTerms() []string {
+	if index.dirty || index.terms == nil {
+		terms := make([]string, 0, len(index.postings))
+		for term := range index.postings {
+			terms = append(terms, term)
+		}
+		sort.Strings(terms)
+		index.terms = terms
+		index.dirty = false
+	}
+	return index.terms
+}
+
+// Search returns the matched messages, latest first
+func (index *MessageIndex) Search(query SearchQuery) []SearchResult {
+	clauses := parseSearchText(query.Text)
+	if len(clauses) == 0 {
+		return nil
+	}
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	var candidates map[string]bool
+	for _, clause := range clauses {
+		keys := index.matchClause(clause)
+		if candidates == nil {
+			candidates = keys
+		} else {
+			for key := range candidates {
+				if !keys[key] {
+					delete(candidates, key)
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+	}
+	results := make([]*indexedMessage, 0, len(candidates))
+	for key := range candidates {
+		if item := index.messages[key]; item != nil && query.accept(item) {
+			results = append(results, item)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].time != results[j].time {
+			return results[i].time > results[j].time
+		}
+		return results[i].sn > results[j].sn
+	})
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	array := make([]SearchResult, 0, len(results))
+	for _, item := range results {
+		array = append(array, SearchResult{
+			Conversation: item.conversation,
+			Sender:       item.sender,
+			SN:           item.sn,
+			Time:         timeFromNano(item.time),
+		})
+	}
+	return array
+}
+
+func (index *MessageIndex) matchClause(clause searchClause) map[string]bool {
+	keys := make(map[string]bool)
+	if clause.prefix {
+		terms := index.sortedTerms()
+		pos := sort.SearchStrings(terms, clause.terms[0])
+		for ; pos < len(terms) && strings.HasPrefix(terms[pos], clause.terms[0]); pos++ {
+			for key := range index.postings[terms[pos]] {
+				keys[key] = true
+			}
+		}
+		return keys
+	}
+	first := index.postings[clause.terms[0]]
+	for key, positions := range first {
+		if index.matchPhrase(key, positions, clause.terms[1:]) {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// matchPhrase checks whether the following terms appear right after the first one
+func (index *MessageIndex) matchPhrase(key string, positions []int, following []string) bool {
+	if len(following) == 0 {
+		return true
+	}
+	for _, start := range positions {
+		matched := true
+		for i, term := range following {
+			if !containsInt(index.postings[term][key], start+i+1) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (query *SearchQuery) accept(item *indexedMessage) bool {
+	if query.Conversation != nil && !query.Conversation.Equal(item.conversation) {
+		return false
+	} else if query.Sender != nil && !query.Sender.Equal(item.sender) {
+		return false
+	} else if !TimeIsNil(query.Since) && item.time < TimestampNano(query.Since) {
+		return false
+	} else if !TimeIsNil(query.Until) && item.time > TimestampNano(query.Until) {
+		return false
+	}
+	return true
+}
+
+type searchClause struct {
+	terms  []string // phrase (or single term)
+	prefix bool
+}
+
+func parseSearchText(text string) []searchClause {
+	clauses := make([]searchClause, 0, 4)
+	parts := strings.Split(text, "\"")
+	for i, part := range parts {
+		if i%2 == 1 {
+			// quoted phrase
+			if terms := TokenizeText(part); len(terms) > 0 {
+				clauses = append(clauses, searchClause{terms: terms})
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+			terms := TokenizeText(word)
+			if len(terms) == 0 {
+				continue
+			} else if prefix && len(terms) == 1 {
+				clauses = append(clauses, searchClause{terms: terms, prefix: true})
+			} else {
+				// "file.png" => phrase ("file", "png")
+				clauses = append(clauses, searchClause{terms: terms})
+			}
+		}
+	}
+	return clauses
+}
+
+func containsInt(array []int, value int) bool {
+	for _, item := range array {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func timeFromNano(nano int64) Time {
+	if nano == 0 {
+		return TimeNil()
+	}
+	return TimeFromFloat64(float64(nano) / 1e9)
+}
+
+/**
+ *  Searchable Message Store
+ *  ~~~~~~~~~~~~~~~~~~~~~~~~
+ *
+ *  Keeps the index updated when messages saved or removed,
+ *  a recalled message is removed by the MessageProcessor (see RECALL).
+ */
+type SearchableMessageStore struct {
+	MessageStore
+
+	Index *MessageIndex
+}
+
+func NewSearchableMessageStore(store MessageStore) *SearchableMessageStore {
+	return &SearchableMessageStore{
+		MessageStore: store,
+		Index:        NewMessageIndex(),
+	}
+}
+
+// Override
+func (store *SearchableMessageStore) SaveMessage(iMsg InstantMessage, conversation ID) bool {
+	if !store.MessageStore.SaveMessage(iMsg, conversation) {
+		return false
+	}
+	store.Index.AddMessage(iMsg, conversation)
+	return true
+}
+
+// Override
+func (store *SearchableMessageStore) RemoveMessage(sn SerialNumberType, conversation ID) bool {
+	store.Index.RemoveMessage(sn, conversation)
+	return store.MessageStore.RemoveMessage(sn, conversation)
+}
+
+// Override
+func (store *SearchableMessageStore) RemoveConversation(conversation ID) bool {
+	store.Index.RemoveConversation(conversation)
+	return store.MessageStore.RemoveConversation(conversation)
+}
+
+// Search returns the matched messages, latest first
+func (store *SearchableMessageStore) Search(query SearchQuery) []InstantMessage {
+	results := store.Index.Search(query)
+	messages := make([]InstantMessage, 0, len(results))
+	for _, item := range results {
+		if msg := store.GetMessage(item.SN, item.Conversation); msg != nil {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,423 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"sort"
+	"sync"
+
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+	. "github.com/dimchat/sdk-go/core"
+)
+
+// ConversationID returns the peer (or group) of the message for the local user
+//
+//	personal message: the other side (sender for inbound, receiver for outbound)
+//	group message:    the group ID
+func ConversationID(iMsg InstantMessage, user ID) ID {
+	group := iMsg.Content().Group()
+	if group == nil {
+		group = iMsg.Group()
+	}
+	target := CipherKeyDestination(iMsg.Receiver(), group)
+	if target.IsGroup() {
+		return target
+	}
+	sender := iMsg.Sender()
+	if sender.Equal(user) {
+		// outbound message
+		return iMsg.Receiver()
+	}
+	return sender
+}
+
+// HistoryQuery is the page of messages in a conversation
+//
+//	Messages are sorted by (time, sn) in ascending order;
+//	to load earlier page, set Before with the first message of the last page.
+type HistoryQuery struct {
+	Before InstantMessage // load messages before this one (nil for latest)
+	After  InstantMessage // load messages after this one (nil for earliest)
+	Limit  int            // max count (0 means no limit)
+}
+
+// MessageStore keeps the instant messages for conversations
+type MessageStore interface {
+
+	// SaveMessage stores the message into conversation, duplicated message (same sn) will be replaced
+	SaveMessage(iMsg InstantMessage, conversation ID) bool
+
+	// GetMessage returns the message with serial number
+	GetMessage(sn SerialNumberType, conversation ID) InstantMessage
+
+	// GetMessages returns a page of messages in ascending order
+	GetMessages(conversation ID, query HistoryQuery) []InstantMessage
+
+	// RemoveMessage deletes the message with serial number
+	RemoveMessage(sn SerialNumberType, conversation ID) bool
+
+	// RemoveConversation deletes all messages in the conversation
+	RemoveConversation(conversation ID) bool
+
+	// Conversations returns all conversation IDs
+	Conversations() []ID
+}
+
+//
+//  Memory Message Store
+//
+
+type storedMessage struct {
+	msg  InstantMessage
+	time int64 // nanoseconds
+	sn   SerialNumberType
+}
+
+func newStoredMessage(iMsg InstantMessage) *storedMessage {
+	item := &storedMessage{
+		msg: iMsg,
+		sn:  iMsg.Content().SN(),
+	}
+	if when := iMsg.Time(); !TimeIsNil(when) {
+		item.time = TimestampNano(when)
+	}
+	return item
+}
+
+func (item *storedMessage) less(other *storedMessage) bool {
+	if item.time != other.time {
+		return item.time < other.time
+	}
+	return item.sn < other.sn
+}
+
+type storedConversation struct {
+	cid      ID
+	messages []*storedMessage                    // sorted by (time, sn)
+	index    map[SerialNumberType]*storedMessage // sn => message
+}
+
+// search returns the position of the first message not less than the item
+func (chat *storedConversation) search(item *storedMessage) int {
+	return sort.Search(len(chat.messages), func(i int) bool {
+		return !chat.messages[i].less(item)
+	})
+}
+
+func (chat *storedConversation) remove(item *storedMessage) {
+	pos := chat.search(item)
+	for ; pos < len(chat.messages); pos++ {
+		if chat.messages[pos] == item {
+			chat.messages = append(chat.messages[:pos], chat.messages[pos+1:]...)
+			break
+		}
+	}
+	delete(chat.index, item.sn)
+}
+
+func (chat *storedConversation) insert(item *storedMessage) {
+	if old := chat.index[item.sn]; old != nil {
+		chat.remove(old)
+	}
+	pos := chat.search(item)
+	chat.messages = append(chat.messages, nil)
+	copy(chat.messages[pos+1:], chat.messages[pos:])
+	chat.messages[pos] = item
+	chat.index[item.sn] = item
+}
+
+// MemoryMessageStore is the default message store in memory
+type MemoryMessageStore struct {
+	mutex         sync.RWMutex
+	conversations map[string]*storedConversation
+}
+
+func NewMemoryMessageStore() *MemoryMessageStore {
+	return &MemoryMessageStore{
+		conversations: make(map[string]*storedConversation, 16),
+	}
+}
+
+// Override
+func (store *MemoryMessageStore) SaveMessage(iMsg InstantMessage, conversation ID) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	key := conversation.String()
+	chat := store.conversations[key]
+	if chat == nil {
+		chat = &storedConversation{
+			cid:      conversation,
+			messages: make([]*storedMessage, 0, 64),
+			index:    make(map[SerialNumberType]*storedMessage, 64),
+		}
+		store.conversations[key] = chat
+	}
+	chat.insert(newStoredMessage(iMsg))
+	return true
+}
+
+// Override
+func (store *MemoryMessageStore) GetMessage(sn SerialNumberType, conversation ID) InstantMessage {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	chat := store.conversations[conversation.String()]
+	if chat == nil {
+		return nil
+	} else if item := chat.index[sn]; item != nil {
+		return item.msg
+	}
+	return nil
+}
+
+// Override
+func (store *MemoryMessageStore) GetMessages(conversation ID, query HistoryQuery) []InstantMessage {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	chat := store.conversations[conversation.String()]
+	if chat == nil {
+		return nil
+	}
+	start := 0
+	end := len(chat.messages)
+	if query.After != nil {
+		after := newStoredMessage(query.After)
+		start = sort.Search(end, func(i int) bool {
+			return after.less(chat.messages[i])
+		})
+	}
+	if query.Before != nil {
+		end = chat.search(newStoredMessage(query.Before))
+	}
+	if start >= end {
+		return nil
+	}
+	if limit := query.Limit; limit > 0 && end-start > limit {
+		if query.Before == nil && query.After != nil {
+			// page forward
+			end = start + limit
+		} else {
+			// page backward
+			start = end - limit
+		}
+	}
+	messages := make([]InstantMessage, 0, end-start)
+	for _, item := range chat.messages[start:end] {
+		messages = append(messages, item.msg)
+	}
+	return messages
+}
+
+// Override
+func (store *MemoryMessageStore) RemoveMessage(sn SerialNumberType, conversation ID) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	chat := store.conversations[conversation.String()]
+	if chat == nil {
+		return false
+	}
+	item := chat.index[sn]
+	if item == nil {
+		return false
+	}
+	chat.remove(item)
+	return true
+}
+
+// Override
+func (store *MemoryMessageStore) RemoveConversation(conversation ID) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	key := conversation.String()
+	if _, exists := store.conversations[key]; !exists {
+		return false
+	}
+	delete(store.conversations, key)
+	return true
+}
+
+// Override
+func (store *MemoryMessageStore) Conversations() []ID {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	array := make([]ID, 0, len(store.conversations))
+	for _, chat := range store.conversations {
+		array = append(array, chat.cid)
+	}
+	return array
+}
+
+// RECALL is the command for withdrawing a message sent before
+//
+//	data format: {
+//	    "type" : i2s(0x88),
+//	    "sn"   : 456,
+//
+//	    "command"   : "recall",
+//	    "origin_sn" : 123,          // serial number of the recalled message
+//	    "group"     : "{GROUP_ID}"  // for group message
+//	}
+//
+// NOTICE: only the sender of the original message can recall it
+const RECALL = "recall"
+
+// NewRecallCommand creates command for recalling the message with serial number
+func NewRecallCommand(sn SerialNumberType, group ID) Command {
+	content := NewBaseCommand(nil, "", RECALL)
+	content.Set("origin_sn", sn)
+	if group != nil {
+		content.SetGroup(group)
+	}
+	return content
+}
+
+// OutboundMessageRecorder stores the messages sent by the local user
+type OutboundMessageRecorder interface {
+
+	// RecordOutboundMessage is called by the send APIs after the message packed
+	RecordOutboundMessage(iMsg InstantMessage)
+}
+
+// recordOutboundMessage passes the message to the messenger if it can record it
+func recordOutboundMessage(messenger Messenger, iMsg InstantMessage) {
+	if recorder, ok := messenger.(OutboundMessageRecorder); ok {
+		recorder.RecordOutboundMessage(iMsg)
+	}
+}
+
+// Override
+func (messenger *BaseMessenger) RecordOutboundMessage(iMsg InstantMessage) {
+	if recorder, ok := messenger.Processor.(OutboundMessageRecorder); ok {
+		recorder.RecordOutboundMessage(iMsg)
+	}
+}
+
+// PackOutgoingMessage encrypts & signs a personal message sent by the local user,
+// and records it into the conversation
+//
+// NOTICE: group messages should be sent by DeliverGroupMessage()
+//
+// Parameters:
+//   - iMsg - Instant message from the local user
+//
+// Returns: Message for sending (nil if the receiver's visa not found)
+func (messenger *BaseMessenger) PackOutgoingMessage(iMsg InstantMessage) ReliableMessage {
+	sMsg := messenger.EncryptMessage(iMsg)
+	if sMsg == nil {
+		// receiver not ready?
+		return nil
+	}
+	rMsg := messenger.SignMessage(sMsg)
+	if rMsg == nil {
+		//panic("failed to sign message")
+		return nil
+	}
+	messenger.RecordOutboundMessage(iMsg)
+	return rMsg
+}
+
+// Override
+func (processor *MessageProcessor) RecordOutboundMessage(iMsg InstantMessage) {
+	processor.storeMessage(iMsg, iMsg.Sender())
+}
+
+// setInboundMessage keeps the decrypted message until its content accepted by the policy
+func (processor *MessageProcessor) setInboundMessage(iMsg InstantMessage, rMsg ReliableMessage) {
+	processor.inboundLock.Lock()
+	defer processor.inboundLock.Unlock()
+	if processor.inbound == nil {
+		processor.inbound = make(map[ReliableMessage]InstantMessage)
+	}
+	processor.inbound[rMsg] = iMsg
+}
+
+// takeInboundMessage removes the decrypted message waiting for the network message
+func (processor *MessageProcessor) takeInboundMessage(rMsg ReliableMessage) InstantMessage {
+	processor.inboundLock.Lock()
+	defer processor.inboundLock.Unlock()
+	iMsg, ok := processor.inbound[rMsg]
+	if !ok {
+		return nil
+	}
+	delete(processor.inbound, rMsg)
+	return iMsg
+}
+
+// storeInboundMessage stores the message after its content accepted by the policy,
+// only the top level content will take it (nested contents come later)
+func (processor *MessageProcessor) storeInboundMessage(rMsg ReliableMessage) {
+	iMsg := processor.takeInboundMessage(rMsg)
+	if iMsg == nil {
+		return
+	}
+	if me := processor.SelectLocalUser(iMsg.Receiver()); me != nil {
+		processor.storeMessage(iMsg, me.ID())
+	}
+}
+
+// protected
+func (processor *MessageProcessor) storeMessage(iMsg InstantMessage, user ID) {
+	store := processor.Store
+	if store == nil || user == nil {
+		return
+	}
+	conversation := ConversationID(iMsg, user)
+	if conversation == nil || conversation.IsBroadcast() {
+		return
+	}
+	if command, ok := iMsg.Content().(Command); ok {
+		if command.CMD() == RECALL {
+			processor.recallMessage(command, iMsg.Sender(), conversation)
+		}
+		// commands & receipts are not conversation history
+		return
+	}
+	store.SaveMessage(iMsg, conversation)
+}
+
+// recallMessage removes the recalled message (and its index) from the store
+func (processor *MessageProcessor) recallMessage(command Command, sender ID, conversation ID) {
+	store := processor.Store
+	sn := ConvertUInt64(command.Get("origin_sn"), 0)
+	if sn == 0 {
+		//panic("recall command error")
+		return
+	}
+	origin := store.GetMessage(sn, conversation)
+	if origin == nil || !origin.Sender().Equal(sender) {
+		// message not found, or not sent by the recaller
+		return
+	}
+	store.RemoveMessage(sn, conversation)
+}
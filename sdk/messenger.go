@@ -34,6 +34,7 @@ import (
 	. "github.com/dimchat/dkd-go/protocol"
 	. "github.com/dimchat/mkm-go/crypto"
 	. "github.com/dimchat/sdk-go/core"
+	. "github.com/dimchat/sdk-go/crypto"
 	. "github.com/dimchat/sdk-go/mkm"
 )
 
@@ -119,7 +120,17 @@ func NewBaseMessenger(facebook EntityDelegate, delegate CipherKeyDelegate) *Base
 func (messenger *BaseMessenger) DeserializeKey(key []byte, sMsg SecureMessage) SymmetricKey {
 	if len(key) == 0 {
 		// get key from cache with direction: sender -> receiver(group)
-		return messenger.GetDecryptKey(sMsg)
+		password := messenger.GetDecryptKey(sMsg)
+		// check key digest for group message
+		msgKeys := sMsg.EncryptedKeys()
+		if msgKeys != nil {
+			digest, _ := msgKeys["digest"].(string)
+			if digest != "" && (password == nil || digest != GetKeyDigest(password)) {
+				// the key renewed, resolve it by digest
+				password = messenger.GetDecryptKeyByDigest(digest, sMsg)
+			}
+		}
+		return password
 	}
 	password := messenger.MessageTransformer.DeserializeKey(key, sMsg)
 	// cache decrypt key when success
@@ -148,6 +159,26 @@ func (messenger *BaseMessenger) GetDecryptKey(sMsg SecureMessage) SymmetricKey {
 	return db.GetCipherKey(sender, target, false)
 }
 
+// GetDecryptKeyByDigest resolves the key by digest when the key store supports it
+func (messenger *BaseMessenger) GetDecryptKeyByDigest(digest string, sMsg SecureMessage) SymmetricKey {
+	db, ok := messenger.CipherKeyDelegate.(DigestCipherKeyDelegate)
+	if !ok {
+		//panic("group key not matched")
+		return nil
+	}
+	sender := sMsg.Sender()
+	target := CipherKeyDestinationForMessage(sMsg)
+	return db.GetCipherKeyByDigest(sender, target, digest)
+}
+
+// CacheEncryptKey stores the (renewed) key for encrypting messages with direction: sender -> receiver(group)
+func (messenger *BaseMessenger) CacheEncryptKey(key SymmetricKey, iMsg InstantMessage) {
+	sender := iMsg.Sender()
+	target := CipherKeyDestinationForMessage(iMsg)
+	db := messenger.CipherKeyDelegate
+	db.CacheCipherKey(sender, target, key)
+}
+
 func (messenger *BaseMessenger) CacheDecryptKey(key SymmetricKey, sMsg SecureMessage) {
 	sender := sMsg.Sender()
 	target := CipherKeyDestinationForMessage(sMsg)
@@ -203,6 +234,20 @@ func (messenger *BaseMessenger) ProcessPackage(data []byte) [][]byte {
 	return processor.ProcessPackage(data)
 }
 
+// ProcessPackageStream emits each response as soon as it's ready (if the processor supports it)
+func (messenger *BaseMessenger) ProcessPackageStream(data []byte, emit func(pack []byte) error) error {
+	processor := messenger.Processor
+	if streamer, ok := processor.(StreamProcessor); ok {
+		return streamer.ProcessPackageStream(data, emit)
+	}
+	for _, res := range processor.ProcessPackage(data) {
+		if err := emit(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Override
 func (messenger *BaseMessenger) ProcessReliableMessage(rMsg ReliableMessage) []ReliableMessage {
 	processor := messenger.Processor
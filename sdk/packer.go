@@ -31,7 +31,12 @@
 package sdk
 
 import (
+	. "github.com/dimchat/core-go/protocol"
 	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/crypto"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/sdk-go/core"
+	. "github.com/dimchat/sdk-go/crypto"
 	. "github.com/dimchat/sdk-go/msg"
 )
 
@@ -47,6 +52,13 @@ type MessagePacker struct {
 	InstantPacker  InstantMessagePacker
 	SecurePacker   SecureMessagePacker
 	ReliablePacker ReliableMessagePacker
+
+	// GroupKeyDelegate tracks which members already hold the group key (optional)
+	//
+	// If set, the group key will only be encrypted for members who don't have it;
+	// a member is treated as a key holder only after ConfirmKeyHolder() is called,
+	// and the renewed key is written through to the messenger's CipherKeyDelegate
+	GroupKeyDelegate GroupKeyDelegate
 }
 
 func NewMessagePacker(facebook Facebook, messenger Messenger) *MessagePacker {
@@ -106,7 +118,11 @@ func (packer *MessagePacker) EncryptMessage(iMsg InstantMessage) SecureMessage {
 		// a station will never send group message, so here must be a client;
 		// the client messenger should check the group's meta & members before encrypting,
 		// so we can trust that the group members MUST exist here.
-		sMsg = packer.InstantPacker.EncryptMessage(iMsg, password, members)
+		if packer.GroupKeyDelegate == nil {
+			sMsg = packer.InstantPacker.EncryptMessage(iMsg, password, members)
+		} else {
+			sMsg = packer.encryptGroupMessage(iMsg, password, members)
+		}
 	} else {
 		// personal message (or split group message)
 		sMsg = packer.InstantPacker.EncryptMessage(iMsg, password, nil)
@@ -127,6 +143,120 @@ func (packer *MessagePacker) EncryptMessage(iMsg InstantMessage) SecureMessage {
 	return sMsg
 }
 
+// protected
+func (packer *MessagePacker) encryptGroupMessage(iMsg InstantMessage, password SymmetricKey, members []ID) SecureMessage {
+	delegate := packer.GroupKeyDelegate
+	sender := iMsg.Sender()
+	group := iMsg.Receiver()
+	digest := GetKeyDigest(password)
+	//
+	//  1. check group membership
+	//
+	keyMembers := delegate.GetKeyMembers(sender, group, digest)
+	if keyMembers == nil {
+		// first time to distribute this key
+		delegate.SaveKeyMembers(members, sender, group, digest)
+	} else if !membersEqual(keyMembers, members) {
+		// membership changed, renew the group key,
+		// so the expelled members cannot decrypt the new messages
+		password = GenerateSymmetricKey(AES)
+		if password == nil {
+			//panic("failed to generate group key")
+			return nil
+		}
+		packer.cacheGroupKey(password, iMsg)
+		digest = GetKeyDigest(password)
+		delegate.SaveKeyMembers(members, sender, group, digest)
+	}
+	//
+	//  2. skip members who already hold the key
+	//
+	holders := delegate.GetKeyHolders(sender, group, digest)
+	targets := make([]ID, 0, len(members))
+	for _, item := range members {
+		if !membersContain(holders, item) {
+			targets = append(targets, item)
+		}
+	}
+	// NOTICE: the targets will not be recorded as key holders here,
+	//         call ConfirmKeyHolder() after the member acknowledged the message,
+	//         otherwise a member who missed this message will never get the key
+	return packer.InstantPacker.EncryptMessage(iMsg, password, targets)
+}
+
+// EncryptKeyCache writes the renewed group key to the messenger's key store
+type EncryptKeyCache interface {
+	CacheEncryptKey(key SymmetricKey, iMsg InstantMessage)
+}
+
+// protected
+func (packer *MessagePacker) cacheGroupKey(password SymmetricKey, iMsg InstantMessage) {
+	if cache, ok := packer.Messenger.(EncryptKeyCache); ok {
+		// write through to the messenger's CipherKeyDelegate,
+		// so GetEncryptKey() will return the renewed key next time
+		cache.CacheEncryptKey(password, iMsg)
+	} else {
+		// NOTICE: GroupKeyDelegate must be the same store as CipherKeyDelegate
+		packer.GroupKeyDelegate.CacheCipherKey(iMsg.Sender(), iMsg.Receiver(), password)
+	}
+}
+
+// ConfirmKeyHolder records the member as holder of the group key,
+// call it when the member acknowledged (receipt) the group message
+//
+// Parameters:
+//   - member - Group member who received the message
+//   - sMsg   - The group message sent (with key digest)
+func (packer *MessagePacker) ConfirmKeyHolder(member ID, sMsg SecureMessage) {
+	delegate := packer.GroupKeyDelegate
+	if delegate == nil {
+		return
+	}
+	msgKeys := sMsg.EncryptedKeys()
+	digest, _ := msgKeys["digest"].(string)
+	if digest == "" {
+		// not a group message with digest
+		return
+	}
+	sender := sMsg.Sender()
+	group := sMsg.Receiver()
+	if !group.IsGroup() {
+		group = sMsg.Group()
+		if group == nil {
+			return
+		}
+	}
+	holders := delegate.GetKeyHolders(sender, group, digest)
+	if membersContain(holders, member) {
+		return
+	}
+	received := make([]ID, 0, len(holders)+1)
+	received = append(received, holders...)
+	received = append(received, member)
+	delegate.SaveKeyHolders(received, sender, group, digest)
+}
+
+func membersContain(members []ID, did ID) bool {
+	for _, item := range members {
+		if item.Equal(did) {
+			return true
+		}
+	}
+	return false
+}
+
+func membersEqual(a, b []ID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, item := range a {
+		if !membersContain(b, item) {
+			return false
+		}
+	}
+	return true
+}
+
 // Override
 func (packer *MessagePacker) SignMessage(sMsg SecureMessage) ReliableMessage {
 	// sign 'data' by sender
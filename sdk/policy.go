@@ -0,0 +1,320 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"sync"
+
+	. "github.com/dimchat/core-go/dkd"
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+/**
+ *  Content Policy
+ *  ~~~~~~~~~~~~~~
+ *
+ *  Rules are evaluated in order, the first matched rule decides
+ *  whether the incoming content is processed, dropped or refused.
+ */
+
+type PolicyAction int
+
+const (
+	PolicyAccept PolicyAction = iota // process the content
+	PolicyDrop                       // drop the content silently
+	PolicyReject                     // drop the content and respond a receipt
+)
+
+func (action PolicyAction) String() string {
+	switch action {
+	case PolicyDrop:
+		return "drop"
+	case PolicyReject:
+		return "reject"
+	default:
+		return "accept"
+	}
+}
+
+type PolicySubject int
+
+const (
+	AnySender      PolicySubject = iota // matches all senders
+	BlockedSender                       // sender or group in block list
+	AllowedSender                       // sender in allow list
+	StrangerSender                      // sender not in allow list
+)
+
+// HANDSHAKE is the command name for handshake between client & station
+const HANDSHAKE = "handshake"
+
+// PolicyStore keeps the block list & allow list
+type PolicyStore interface {
+
+	// IsBlocked checks whether the user/group is in block list
+	IsBlocked(did ID) bool
+
+	// IsAllowed checks whether the user is in allow list (e.g.: contacts)
+	IsAllowed(did ID) bool
+}
+
+// MemoryPolicyStore is the default policy store in memory
+type MemoryPolicyStore struct {
+	mutex   sync.RWMutex
+	blocked map[string]bool
+	allowed map[string]bool
+}
+
+func NewMemoryPolicyStore() *MemoryPolicyStore {
+	return &MemoryPolicyStore{
+		blocked: make(map[string]bool, 16),
+		allowed: make(map[string]bool, 64),
+	}
+}
+
+// Override
+func (store *MemoryPolicyStore) IsBlocked(did ID) bool {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	return store.blocked[did.String()]
+}
+
+// Override
+func (store *MemoryPolicyStore) IsAllowed(did ID) bool {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	return store.allowed[did.String()]
+}
+
+func (store *MemoryPolicyStore) Block(did ID) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.blocked[did.String()] = true
+}
+
+func (store *MemoryPolicyStore) Unblock(did ID) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.blocked, did.String())
+}
+
+func (store *MemoryPolicyStore) Allow(did ID) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.allowed[did.String()] = true
+}
+
+func (store *MemoryPolicyStore) Disallow(did ID) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.allowed, did.String())
+}
+
+// PolicyRule matches the incoming content,
+// empty fields match everything
+type PolicyRule struct {
+	Name string
+
+	Subject  PolicySubject
+	Senders  []ID
+	Groups   []ID
+	Types    []MessageType
+	Commands []string
+
+	Action PolicyAction
+}
+
+func (rule *PolicyRule) Match(content Content, rMsg ReliableMessage, store PolicyStore) bool {
+	sender := rMsg.Sender()
+	group := content.Group()
+	if group == nil {
+		group = rMsg.Group()
+	}
+	if group == nil && rMsg.Receiver().IsGroup() {
+		// group message without 'group' field
+		group = rMsg.Receiver()
+	}
+	// check subject
+	switch rule.Subject {
+	case BlockedSender:
+		if store == nil {
+			return false
+		} else if !store.IsBlocked(sender) && (group == nil || !store.IsBlocked(group)) {
+			return false
+		}
+	case AllowedSender:
+		if store == nil || !store.IsAllowed(sender) {
+			return false
+		}
+	case StrangerSender:
+		if store != nil && store.IsAllowed(sender) {
+			return false
+		}
+	}
+	// check sender & group
+	if len(rule.Senders) > 0 && !membersContain(rule.Senders, sender) {
+		return false
+	}
+	if len(rule.Groups) > 0 && (group == nil || !membersContain(rule.Groups, group)) {
+		return false
+	}
+	// check content type & command name
+	if len(rule.Types) > 0 && !policyContainsString(rule.Types, content.Type()) {
+		return false
+	}
+	if len(rule.Commands) > 0 {
+		command, ok := content.(Command)
+		if !ok || !policyContainsString(rule.Commands, command.CMD()) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContentPolicy evaluates rules in order
+type ContentPolicy struct {
+	mutex sync.RWMutex
+	rules []*PolicyRule
+
+	Store PolicyStore
+
+	// DefaultAction is used when no rule matched
+	DefaultAction PolicyAction
+}
+
+func NewContentPolicy(store PolicyStore) *ContentPolicy {
+	return &ContentPolicy{
+		rules:         make([]*PolicyRule, 0, 8),
+		Store:         store,
+		DefaultAction: PolicyAccept,
+	}
+}
+
+// NewDefaultContentPolicy creates policy with rules:
+//
+//  1. drop all contents from blocked users/groups;
+//  2. accept handshake/meta/documents commands from strangers;
+//  3. reject other contents from strangers.
+func NewDefaultContentPolicy(store PolicyStore) *ContentPolicy {
+	policy := NewContentPolicy(store)
+	policy.AddRule(&PolicyRule{
+		Name:    "blocked",
+		Subject: BlockedSender,
+		Action:  PolicyDrop,
+	})
+	policy.AddRule(&PolicyRule{
+		Name:     "stranger-commands",
+		Subject:  StrangerSender,
+		Commands: []string{HANDSHAKE, META, DOCUMENTS},
+		Action:   PolicyAccept,
+	})
+	policy.AddRule(&PolicyRule{
+		Name:    "stranger",
+		Subject: StrangerSender,
+		Action:  PolicyReject,
+	})
+	return policy
+}
+
+func (policy *ContentPolicy) AddRule(rule *PolicyRule) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.rules = append(policy.rules, rule)
+}
+
+// InsertRule adds the rule before others
+func (policy *ContentPolicy) InsertRule(rule *PolicyRule) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	policy.rules = append([]*PolicyRule{rule}, policy.rules...)
+}
+
+func (policy *ContentPolicy) RemoveRule(name string) {
+	policy.mutex.Lock()
+	defer policy.mutex.Unlock()
+	rules := make([]*PolicyRule, 0, len(policy.rules))
+	for _, item := range policy.rules {
+		if item.Name != name {
+			rules = append(rules, item)
+		}
+	}
+	policy.rules = rules
+}
+
+func (policy *ContentPolicy) Rules() []*PolicyRule {
+	policy.mutex.RLock()
+	defer policy.mutex.RUnlock()
+	rules := make([]*PolicyRule, len(policy.rules))
+	copy(rules, policy.rules)
+	return rules
+}
+
+// Evaluate returns the action with the matched rule (nil for default)
+func (policy *ContentPolicy) Evaluate(content Content, rMsg ReliableMessage) (PolicyAction, *PolicyRule) {
+	for _, rule := range policy.Rules() {
+		if rule.Match(content, rMsg, policy.Store) {
+			return rule.Action, rule
+		}
+	}
+	return policy.DefaultAction, nil
+}
+
+// respondReceipt builds receipt for the content refused by the processor,
+// never respond receipt for receipt
+func respondReceipt(text string, content Content, rMsg ReliableMessage, template string, replacements StringKeyMap) []Content {
+	if isReceiptCommand(content) {
+		return nil
+	}
+	res := NewReceiptCommand(text, rMsg.Envelope(), content)
+	if group := content.Group(); group != nil {
+		res.SetGroup(group)
+	}
+	res.Set("template", template)
+	res.Set("replacements", replacements)
+	return []Content{res}
+}
+
+func isReceiptCommand(content Content) bool {
+	command, ok := content.(Command)
+	return ok && command.CMD() == RECEIPT
+}
+
+func policyContainsString(array []string, str string) bool {
+	for _, item := range array {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
+)
+
+// PrivacyDataSource keeps the block list & mute list for local users
+//
+//	block list: messages from these users/groups will be dropped before decryption
+//	mute list:  messages from these users/groups will be processed without notification
+type PrivacyDataSource interface {
+	GetBlockList(user ID) []ID
+	GetMuteList(user ID) []ID
+
+	SaveBlockList(list []ID, user ID) bool
+	SaveMuteList(list []ID, user ID) bool
+}
+
+var sharedPrivacyDataSource PrivacyDataSource = nil
+
+func SetPrivacyDataSource(dataSource PrivacyDataSource) {
+	sharedPrivacyDataSource = dataSource
+}
+
+func GetPrivacyDataSource() PrivacyDataSource {
+	return sharedPrivacyDataSource
+}
+
+// local key for muted message (processing only, not serialized)
+//
+// NOTICE: the value is of type mutedFlag, which cannot be decoded from network data,
+//
+//	so a relay cannot mute the message by adding this key
+const mutedMessageKey = "_muted"
+
+type mutedFlag struct{}
+
+// IsMutedMessage checks whether the notification should be skipped
+func IsMutedMessage(msg Mapper) bool {
+	_, muted := msg.Get(mutedMessageKey).(mutedFlag)
+	return muted
+}
+
+func setMutedMessage(msg Mapper) {
+	msg.Set(mutedMessageKey, mutedFlag{})
+}
+
+// protected
+func (processor *MessageProcessor) isBlocked(receiver, sender, group ID) bool {
+	dataSource := GetPrivacyDataSource()
+	if dataSource == nil {
+		return false
+	}
+	user := processor.SelectLocalUser(receiver)
+	if user == nil {
+		return false
+	}
+	if group == nil && receiver.IsGroup() {
+		// group message without 'group' field
+		group = receiver
+	}
+	return privacyListMatch(dataSource.GetBlockList(user.ID()), sender, group)
+}
+
+// protected
+func (processor *MessageProcessor) isMuted(iMsg InstantMessage) bool {
+	dataSource := GetPrivacyDataSource()
+	if dataSource == nil {
+		return false
+	}
+	user := processor.SelectLocalUser(iMsg.Receiver())
+	if user == nil {
+		return false
+	}
+	group := iMsg.Content().Group()
+	if group == nil {
+		group = iMsg.Group()
+	}
+	if group == nil && iMsg.Receiver().IsGroup() {
+		// group message without 'group' field
+		group = iMsg.Receiver()
+	}
+	return privacyListMatch(dataSource.GetMuteList(user.ID()), iMsg.Sender(), group)
+}
+
+func privacyListMatch(list []ID, sender, group ID) bool {
+	for _, item := range list {
+		if item.Equal(sender) || (group != nil && item.Equal(group)) {
+			return true
+		}
+	}
+	return false
+}
@@ -31,8 +31,13 @@
 package sdk
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	. "github.com/dimchat/core-go/protocol"
 	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/types"
 	. "github.com/dimchat/sdk-go/dkd"
 )
 
@@ -47,12 +52,42 @@ type MessageProcessor struct {
 	//
 	// Enables type-specific processing (text, file, command, etc.)
 	Factory ContentProcessorFactory
+
+	// Timeout is the time budget for each content processor (0 means no limit)
+	//
+	// The deadline is passed to ContextContentProcessor, processors run in the
+	// calling goroutine, so the ones ignoring the context cannot be interrupted
+	Timeout time.Duration
+
+	// ErrorHandler is called when a content processor panics or times out
+	ErrorHandler ProcessorErrorHandler
+
+	// Policy decides whether the incoming content should be processed (nil to accept all)
+	Policy *ContentPolicy
+
+	// RateLimiter throttles the senders (nil for no limit)
+	RateLimiter *RateLimiter
+
+	// Store keeps the inbound & outbound messages, except commands (nil to disable)
+	//
+	// Outbound messages are recorded by the send APIs: PackOutgoingMessage(),
+	// FanOutGroupMessage() & RelayGroupMessage(), responses are not recorded
+	Store MessageStore
+
+	// decrypted messages waiting for their contents accepted (see ProcessContent)
+	inbound     map[ReliableMessage]InstantMessage
+	inboundLock sync.Mutex
 }
 
 func NewMessageProcessor(facebook Facebook, messenger Messenger) *MessageProcessor {
 	return &MessageProcessor{
-		TwinsHelper: NewTwinsHelper(facebook, messenger),
-		Factory:     CreateContentProcessorFactory(facebook, messenger),
+		TwinsHelper:  NewTwinsHelper(facebook, messenger),
+		Factory:      CreateContentProcessorFactory(facebook, messenger),
+		Timeout:      0,
+		ErrorHandler: nil,
+		Policy:       nil,
+		RateLimiter:  nil,
+		Store:        nil,
 	}
 }
 
@@ -84,6 +119,44 @@ func (processor *MessageProcessor) ProcessPackage(data []byte) [][]byte {
 	return packages
 }
 
+// ProcessPackageStream processes the package like ProcessPackage,
+// but each response is written by emit as soon as it's signed & serialized
+//
+// Returns: the error returned by emit
+func (processor *MessageProcessor) ProcessPackageStream(data []byte, emit func(pack []byte) error) error {
+	messenger := processor.Messenger
+	// 1. deserialize message
+	rMsg := messenger.DeserializeMessage(data)
+	if rMsg == nil {
+		// no valid message received
+		return nil
+	}
+	// 2. verify message
+	sMsg := messenger.VerifyMessage(rMsg)
+	if sMsg == nil {
+		return nil
+	}
+	// 3. process message
+	responses := messenger.ProcessSecureMessage(sMsg, rMsg)
+	// 4. sign & serialize each response
+	for _, res := range responses {
+		msg := messenger.SignMessage(res)
+		if msg == nil {
+			// should not happen
+			continue
+		}
+		pack := messenger.SerializeMessage(msg)
+		if len(pack) == 0 {
+			// should not happen
+			continue
+		}
+		if err := emit(pack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Override
 func (processor *MessageProcessor) ProcessReliableMessage(rMsg ReliableMessage) []ReliableMessage {
 	// TODO: override to check broadcast message before calling it
@@ -117,12 +190,24 @@ func (processor *MessageProcessor) ProcessReliableMessage(rMsg ReliableMessage)
 // Override
 func (processor *MessageProcessor) ProcessSecureMessage(sMsg SecureMessage, rMsg ReliableMessage) []SecureMessage {
 	messenger := processor.Messenger
+	// 0. check block list
+	if processor.isBlocked(sMsg.Receiver(), sMsg.Sender(), sMsg.Group()) {
+		// drop the message from blocked user/group
+		return nil
+	}
 	// 1. decrypt message
 	iMsg := messenger.DecryptMessage(sMsg)
 	if iMsg == nil {
 		// cannot decrypt this message, not for you?
 		// delivering message to other receiver?
 		return nil
+	} else if group := iMsg.Content().Group(); group != nil && processor.isBlocked(iMsg.Receiver(), iMsg.Sender(), group) {
+		// group ID hidden in content
+		return nil
+	} else if processor.isMuted(iMsg) {
+		// process it without notification
+		setMutedMessage(iMsg)
+		setMutedMessage(rMsg)
 	}
 	// 2. process message
 	responses := messenger.ProcessInstantMessage(iMsg, rMsg)
@@ -146,6 +231,11 @@ func (processor *MessageProcessor) ProcessSecureMessage(sMsg SecureMessage, rMsg
 // Override
 func (processor *MessageProcessor) ProcessInstantMessage(iMsg InstantMessage, rMsg ReliableMessage) []InstantMessage {
 	messenger := processor.Messenger
+	// 0. store inbound message after its content accepted (see ProcessContent)
+	if processor.Store != nil {
+		processor.setInboundMessage(iMsg, rMsg)
+		defer processor.takeInboundMessage(rMsg)
+	}
 	// 1. process content
 	responses := messenger.ProcessContent(iMsg.Content(), rMsg)
 	if len(responses) == 0 {
@@ -173,6 +263,27 @@ func (processor *MessageProcessor) ProcessInstantMessage(iMsg InstantMessage, rM
 // Override
 func (processor *MessageProcessor) ProcessContent(content Content, rMsg ReliableMessage) []Content {
 	// TODO: override to check group
+	if policy := processor.Policy; policy != nil {
+		action, _ := policy.Evaluate(content, rMsg)
+		if action == PolicyDrop {
+			return nil
+		} else if action == PolicyReject {
+			return respondReceipt("Content rejected.", content, rMsg,
+				"Content (type: ${type}) not accepted by receiver.", StringKeyMap{
+					"type": content.Type(),
+				})
+		}
+	}
+	if limiter := processor.RateLimiter; limiter != nil && !limiter.Allow(content, rMsg) {
+		if limiter.OverLimit != PolicyReject {
+			return nil
+		}
+		return respondReceipt("Too many requests.", content, rMsg,
+			"Too many requests (${kind}), please try again later.", StringKeyMap{
+				"kind": rateLimitKind(content),
+			})
+	}
+	processor.storeInboundMessage(rMsg)
 	factory := processor.Factory
 	cpu := factory.GetContentProcessor(content)
 	if cpu == nil {
@@ -183,10 +294,41 @@ func (processor *MessageProcessor) ProcessContent(content Content, rMsg Reliable
 			return nil
 		}
 	}
-	return cpu.ProcessContent(content, rMsg)
+	responses, err := processor.runProcessor(cpu, content, rMsg)
+	if err != nil {
+		if handler := processor.ErrorHandler; handler != nil {
+			handler(err, content, rMsg)
+		}
+		// the panic value is not included
+		return respondReceipt("Content processing failed.", content, rMsg,
+			"Failed to process content (type: ${type}, command: ${command}): ${error}.", StringKeyMap{
+				"type":    err.ContentType,
+				"command": err.Command,
+				"error":   err.Reason,
+			})
+	}
+	return responses
 	// TODO: override to filter the response
 }
 
+// protected
+func (processor *MessageProcessor) runProcessor(cpu ContentProcessor, content Content, rMsg ReliableMessage) ([]Content, *ProcessorError) {
+	timeout := processor.Timeout
+	if timeout <= 0 {
+		return safeProcessContent(context.Background(), cpu, content, rMsg)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// NOTICE: run in this goroutine, the processor shares the content & message maps,
+	//         so it must have finished before returning
+	responses, err := safeProcessContent(ctx, cpu, content, rMsg)
+	if err == nil && ctx.Err() == context.DeadlineExceeded {
+		// too late, drop the result
+		return nil, newProcessorError(ProcessorTimeout, nil, content)
+	}
+	return responses, err
+}
+
 //
 //  CPU Factory Helper
 //
@@ -0,0 +1,112 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/sdk-go/dkd"
+)
+
+// DefaultProcessorTimeout is the suggested time budget for each content processor,
+// set it to MessageProcessor.Timeout to enable the deadline
+var DefaultProcessorTimeout = 30 * time.Second
+
+const (
+	ProcessorPanic   = "panic"
+	ProcessorTimeout = "timeout"
+)
+
+// ProcessorError describes a content processor failure
+type ProcessorError struct {
+
+	// Reason is ProcessorPanic or ProcessorTimeout
+	Reason string
+
+	// Value is the recovered panic value
+	Value interface{}
+
+	// Stack is the stack trace when panic
+	Stack []byte
+
+	// ContentType is the type of content processing
+	ContentType MessageType
+
+	// Command is the name of command processing (empty for other contents)
+	Command string
+}
+
+func (err *ProcessorError) Error() string {
+	if err.Reason == ProcessorPanic {
+		return fmt.Sprintf("content processor panic: %v (type: %s, command: %s)",
+			err.Value, err.ContentType, err.Command)
+	}
+	return fmt.Sprintf("content processor %s (type: %s, command: %s)",
+		err.Reason, err.ContentType, err.Command)
+}
+
+// ProcessorErrorHandler is the hook for content processor failures
+type ProcessorErrorHandler func(err *ProcessorError, content Content, rMsg ReliableMessage)
+
+func newProcessorError(reason string, value interface{}, content Content) *ProcessorError {
+	err := &ProcessorError{
+		Reason:      reason,
+		Value:       value,
+		ContentType: content.Type(),
+	}
+	if command, ok := content.(Command); ok {
+		err.Command = command.CMD()
+	}
+	if reason == ProcessorPanic {
+		err.Stack = debug.Stack()
+	}
+	return err
+}
+
+func safeProcessContent(ctx context.Context, cpu ContentProcessor, content Content, rMsg ReliableMessage) (responses []Content, err *ProcessorError) {
+	defer func() {
+		if r := recover(); r != nil {
+			responses = nil
+			err = newProcessorError(ProcessorPanic, r, content)
+		}
+	}()
+	if ccp, ok := cpu.(ContextContentProcessor); ok {
+		responses = ccp.ProcessContentWithContext(ctx, content, rMsg)
+	} else {
+		responses = cpu.ProcessContent(content, rMsg)
+	}
+	return responses, nil
+}
@@ -0,0 +1,267 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2021 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2021 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+)
+
+// RateQuota is the token bucket config
+type RateQuota struct {
+	Rate  float64 // tokens refilled per second
+	Burst float64 // bucket capacity
+}
+
+// DefaultRateQuotas limits the queries which respond with large data,
+// meta/documents commands carrying data (responses) are not limited
+var DefaultRateQuotas = map[string]RateQuota{
+	META:      {Rate: 0.2, Burst: 5},
+	DOCUMENTS: {Rate: 0.2, Burst: 5},
+}
+
+// MaxRateBuckets limits the buckets kept by the limiter,
+// the least recently used one will be removed when full
+var MaxRateBuckets = 65536
+
+// MaxThrottleStats limits the throttled senders kept for metrics,
+// the least recently throttled one will be removed when full
+var MaxThrottleStats = 4096
+
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func (bucket *tokenBucket) take(quota RateQuota, now time.Time) bool {
+	elapsed := now.Sub(bucket.last).Seconds()
+	if elapsed > 0 {
+		bucket.tokens += elapsed * quota.Rate
+		if bucket.tokens > quota.Burst {
+			bucket.tokens = quota.Burst
+		}
+		bucket.last = now
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens -= 1
+	return true
+}
+
+// ThrottleStats is the metrics of throttled sender
+type ThrottleStats struct {
+	Sender    ID
+	Kind      string // command name or content type
+	Count     uint64
+	FirstTime time.Time
+	LastTime  time.Time
+}
+
+/**
+ *  Rate Limiter
+ *  ~~~~~~~~~~~~
+ *
+ *  Token bucket for each sender & command name (or content type)
+ */
+type RateLimiter struct {
+	mutex   sync.Mutex
+	quotas  map[string]RateQuota
+	buckets map[string]*list.Element // key => *tokenBucket
+	recent  *list.List               // buckets in recently used order (front is the latest)
+	stats   map[string]*ThrottleStats
+	total   uint64
+
+	// OverLimit is PolicyDrop or PolicyReject
+	OverLimit PolicyAction
+}
+
+func NewRateLimiter() *RateLimiter {
+	limiter := &RateLimiter{
+		quotas:    make(map[string]RateQuota, len(DefaultRateQuotas)),
+		buckets:   make(map[string]*list.Element, 1024),
+		recent:    list.New(),
+		stats:     make(map[string]*ThrottleStats, 64),
+		OverLimit: PolicyDrop,
+	}
+	for kind, quota := range DefaultRateQuotas {
+		limiter.quotas[kind] = quota
+	}
+	return limiter
+}
+
+// SetQuota sets quota for command name or content type,
+// zero rate to remove the limit
+func (limiter *RateLimiter) SetQuota(kind string, quota RateQuota) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if quota.Rate <= 0 {
+		delete(limiter.quotas, kind)
+	} else {
+		limiter.quotas[kind] = quota
+	}
+}
+
+func (limiter *RateLimiter) GetQuota(kind string) (RateQuota, bool) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	quota, ok := limiter.quotas[kind]
+	return quota, ok
+}
+
+// Allow takes a token from the sender's bucket
+func (limiter *RateLimiter) Allow(content Content, rMsg ReliableMessage) bool {
+	return limiter.AllowAt(content, rMsg, time.Now())
+}
+
+func (limiter *RateLimiter) AllowAt(content Content, rMsg ReliableMessage, now time.Time) bool {
+	if !isRateLimitedContent(content) {
+		return true
+	}
+	kind := rateLimitKind(content)
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	quota, ok := limiter.quotas[kind]
+	if !ok {
+		// no limit
+		return true
+	}
+	sender := rMsg.Sender()
+	key := sender.String() + "|" + kind
+	var bucket *tokenBucket
+	if elem := limiter.buckets[key]; elem != nil {
+		limiter.recent.MoveToFront(elem)
+		bucket = elem.Value.(*tokenBucket)
+	} else {
+		if len(limiter.buckets) >= MaxRateBuckets {
+			limiter.removeOldestBucket()
+		}
+		bucket = &tokenBucket{key: key, tokens: quota.Burst, last: now}
+		limiter.buckets[key] = limiter.recent.PushFront(bucket)
+	}
+	if bucket.take(quota, now) {
+		return true
+	}
+	// throttled
+	limiter.total += 1
+	stats := limiter.stats[key]
+	if stats == nil {
+		if len(limiter.stats) >= MaxThrottleStats {
+			limiter.removeOldestStats()
+		}
+		stats = &ThrottleStats{
+			Sender:    sender,
+			Kind:      kind,
+			FirstTime: now,
+		}
+		limiter.stats[key] = stats
+	}
+	stats.Count += 1
+	stats.LastTime = now
+	return false
+}
+
+// removeOldestBucket removes the least recently used bucket
+func (limiter *RateLimiter) removeOldestBucket() {
+	elem := limiter.recent.Back()
+	if elem == nil {
+		return
+	}
+	limiter.recent.Remove(elem)
+	delete(limiter.buckets, elem.Value.(*tokenBucket).key)
+}
+
+// removeOldestStats removes the least recently throttled sender
+func (limiter *RateLimiter) removeOldestStats() {
+	var oldest string
+	var lastTime time.Time
+	for key, stats := range limiter.stats {
+		if oldest == "" || stats.LastTime.Before(lastTime) {
+			oldest = key
+			lastTime = stats.LastTime
+		}
+	}
+	delete(limiter.stats, oldest)
+}
+
+// TotalThrottled returns the count of all throttled contents
+func (limiter *RateLimiter) TotalThrottled() uint64 {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.total
+}
+
+// ThrottledSenders returns metrics sorted by count (descending)
+func (limiter *RateLimiter) ThrottledSenders() []ThrottleStats {
+	limiter.mutex.Lock()
+	array := make([]ThrottleStats, 0, len(limiter.stats))
+	for _, stats := range limiter.stats {
+		array = append(array, *stats)
+	}
+	limiter.mutex.Unlock()
+	sort.Slice(array, func(i, j int) bool {
+		return array[i].Count > array[j].Count
+	})
+	return array
+}
+
+// ResetMetrics clears the throttled senders
+func (limiter *RateLimiter) ResetMetrics() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.stats = make(map[string]*ThrottleStats, 64)
+	limiter.total = 0
+}
+
+// isRateLimitedContent checks whether the content is a query,
+// meta/documents commands carrying data are responses
+func isRateLimitedContent(content Content) bool {
+	if command, ok := content.(DocumentCommand); ok {
+		return command.Documents() == nil
+	} else if command, ok := content.(MetaCommand); ok {
+		return command.Meta() == nil
+	}
+	return true
+}
+
+func rateLimitKind(content Content) string {
+	if command, ok := content.(Command); ok {
+		return command.CMD()
+	}
+	return content.Type()
+}
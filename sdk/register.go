@@ -0,0 +1,292 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	. "github.com/dimchat/core-go/protocol"
+	. "github.com/dimchat/mkm-go/crypto"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/sdk-go/core"
+	. "github.com/dimchat/sdk-go/mkm"
+)
+
+// Private key usages for account registration
+const (
+	// META_KEY marks the identity key paired with meta.key (signs meta & visa)
+	META_KEY = "M"
+
+	// VISA_KEY marks the communication key paired with visa.key (decrypts messages)
+	VISA_KEY = "V"
+)
+
+// AccountKeeper defines the persistent storage required for account registration
+//
+// Extends Archivist with the private keys and group members,
+// which must be saved together with the new meta & documents
+type AccountKeeper interface {
+	Archivist
+
+	// SavePrivateKey stores a private key for the local user
+	//
+	// Parameters:
+	//   - key     - Private key to save
+	//   - keyType - Key usage (META_KEY/VISA_KEY)
+	//   - uid     - Local user ID
+	// Returns: true if the key saved successfully, false otherwise
+	SavePrivateKey(key PrivateKey, keyType string, uid ID) bool
+
+	// SaveMembers stores the member list for the group
+	//
+	// Parameters:
+	//   - members - Group member IDs (the founder must be the first one)
+	//   - gid     - Group ID
+	// Returns: true if members saved successfully, false otherwise
+	SaveMembers(members []ID, gid ID) bool
+}
+
+// Register creates new user/group accounts in one call
+//
+//	User Registration:
+//	    1. generate private key (identity key)
+//	    2. generate meta with the identity key
+//	    3. generate ID with meta & network
+//	    4. generate visa with a new communication key and sign it with the identity key
+//	    5. save private keys, meta & visa
+//
+//	Group Registration:
+//	    1. get the founder's identity key
+//	    2. generate meta with the founder's key
+//	    3. generate ID with meta & network
+//	    4. generate bulletin and sign it with the founder's key
+//	    5. save meta, bulletin & members (founder as the first member)
+type Register struct {
+
+	// Facebook provides entity creation and meta/document persistence
+	Facebook Facebook
+
+	// Database saves private keys & group members
+	Database AccountKeeper
+}
+
+func NewRegister(facebook Facebook, db AccountKeeper) *Register {
+	return &Register{
+		Facebook: facebook,
+		Database: db,
+	}
+}
+
+// CreateUser generates a new local user account
+//
+// Parameters:
+//   - version  - Meta type (MKM/BTC/ETH/...)
+//   - network  - ID type (USER/STATION/BOT/...)
+//   - seed     - ID.name for meta types with seed (ignored by BTC/ETH)
+//   - nickname - Name in visa
+//   - avatar   - Avatar in visa (optional)
+//
+// Returns: New User instance (nil on failure)
+func (register *Register) CreateUser(version MetaType, network EntityType, seed, nickname string, avatar TransportableFile) User {
+	if !EntityTypeIsUser(network) {
+		//panic("user type error")
+		return nil
+	}
+	db := register.Database
+	//
+	//  1. generate private key (ECC is required by BTC/ETH meta)
+	//
+	idKey := GeneratePrivateKey(ECC)
+	if idKey == nil {
+		//panic("failed to generate private key")
+		return nil
+	}
+	//
+	//  2. generate meta
+	//
+	meta := GenerateMeta(version, idKey, metaSeed(version, seed))
+	if meta == nil {
+		//panic("failed to generate meta")
+		return nil
+	}
+	//
+	//  3. generate ID
+	//
+	uid := GenerateID(meta, network, "")
+	if uid == nil {
+		//panic("failed to generate ID")
+		return nil
+	}
+	//
+	//  4. generate visa with new private key (RSA for encryption)
+	//
+	msgKey := GeneratePrivateKey(RSA)
+	if msgKey == nil {
+		//panic("failed to generate visa key")
+		return nil
+	}
+	// identity key must be saved before signing visa
+	if !db.SavePrivateKey(idKey, META_KEY, uid) {
+		return nil
+	} else if !db.SavePrivateKey(msgKey, VISA_KEY, uid) {
+		return nil
+	}
+	visa := register.createVisa(uid, msgKey.PublicKey(), nickname, avatar)
+	if visa == nil {
+		return nil
+	}
+	//
+	//  5. save meta & visa
+	//
+	facebook := register.Facebook
+	if !facebook.SaveMeta(meta, uid) {
+		return nil
+	} else if !facebook.SaveDocument(visa, uid) {
+		return nil
+	}
+	return facebook.GetUser(uid)
+}
+
+// CreateGroup generates a new group founded by the local user
+//
+// Parameters:
+//   - founder - Local user ID (must have the identity key)
+//   - version - Meta type (MKM as default, the seed is required)
+//   - network - ID type (GROUP/...)
+//   - title   - Name in bulletin
+//
+// Returns: New Group instance (nil on failure)
+func (register *Register) CreateGroup(founder ID, version MetaType, network EntityType, title string) Group {
+	if !EntityTypeIsGroup(network) {
+		//panic("group type error")
+		return nil
+	}
+	facebook := register.Facebook
+	//
+	//  1. get private key
+	//
+	sKey := facebook.GetPrivateKeyForVisaSignature(founder)
+	if sKey == nil {
+		//panic("failed to get private key for founder: " + founder.String())
+		return nil
+	}
+	//
+	//  2. generate meta
+	//
+	meta := GenerateMeta(version, sKey, metaSeed(version, groupSeed()))
+	if meta == nil {
+		//panic("failed to generate meta")
+		return nil
+	}
+	//
+	//  3. generate ID
+	//
+	gid := GenerateID(meta, network, "")
+	if gid == nil {
+		//panic("failed to generate ID")
+		return nil
+	}
+	//
+	//  4. generate bulletin signed by founder
+	//
+	doc := CreateDocument(BULLETIN, "", nil)
+	bulletin, ok := doc.(Bulletin)
+	if !ok {
+		//panic("failed to create bulletin")
+		return nil
+	}
+	bulletin.Set("did", gid.String())
+	bulletin.SetName(title)
+	bulletin.SetProperty("founder", founder.String())
+	if bulletin.Sign(sKey) == nil {
+		//panic("failed to sign bulletin")
+		return nil
+	}
+	//
+	//  5. save meta, bulletin & members
+	//
+	if !facebook.SaveMeta(meta, gid) {
+		return nil
+	} else if !facebook.SaveDocument(bulletin, gid) {
+		return nil
+	}
+	db := register.Database
+	if !db.SaveMembers([]ID{founder}, gid) {
+		return nil
+	}
+	return facebook.GetGroup(gid)
+}
+
+// protected
+func (register *Register) createVisa(uid ID, pKey PublicKey, nickname string, avatar TransportableFile) Visa {
+	doc := CreateDocument(VISA, "", nil)
+	visa, ok := doc.(Visa)
+	if !ok {
+		//panic("failed to create visa")
+		return nil
+	}
+	visa.Set("did", uid.String())
+	visa.SetName(nickname)
+	if avatar != nil {
+		visa.SetAvatar(avatar)
+	}
+	if encKey, ok := pKey.(EncryptKey); ok {
+		visa.SetPublicKey(encKey)
+	} else {
+		//panic("visa key error")
+		return nil
+	}
+	// the user is not cached yet (no visa), so create it here to sign the visa
+	// with the private key paired with meta.key
+	user := NewBaseUser(uid)
+	user.SetDataSource(register.Facebook)
+	return user.SignVisa(visa)
+}
+
+// metaSeed returns the seed for meta types which contain ID.name
+func metaSeed(version MetaType, seed string) string {
+	switch version {
+	case MKM, ExBTC, ExETH:
+		return seed
+	default:
+		// BTC/ETH address is generated from the key data directly
+		return ""
+	}
+}
+
+// groupSeed returns a random seed for group meta (ID.name)
+func groupSeed() string {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// 10,000 ~ 999,999,999
+	return fmt.Sprintf("Group-%d", r.Int63n(999990000)+10000)
+}
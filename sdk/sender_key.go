@@ -0,0 +1,88 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	. "github.com/dimchat/dkd-go/protocol"
+	. "github.com/dimchat/mkm-go/protocol"
+	. "github.com/dimchat/sdk-go/msg"
+)
+
+// DistributeSenderKey renews the sender's chain key for the group when needed,
+// saves it for the sender self, and builds the commands for the other members
+//
+//	The chain will be renewed when:
+//	    1. no chain for this group yet;
+//	    2. the chain is exhausted;
+//	    3. the group membership changed (so the removed members cannot decrypt new messages);
+//	    4. force is true.
+//
+// Parameters:
+//   - sender - Local user ID
+//   - group  - Group ID
+//   - force  - Renew the chain even if it's still valid
+//
+// Returns: Personal messages carrying the chain key for each member (nil if no need to distribute),
+// they should be sent before the next group message
+func (packer *MessagePacker) DistributeSenderKey(sender, group ID, force bool) []InstantMessage {
+	delegate := GetSenderKeyDelegate()
+	if delegate == nil {
+		//panic("sender key delegate not set")
+		return nil
+	}
+	members := packer.Facebook.GetMembers(group)
+	if len(members) == 0 {
+		//panic("group not ready")
+		return nil
+	}
+	old := delegate.GetSenderKey(sender, group)
+	if !force && old != nil && old.Iteration < MaxSenderKeyIteration && old.IsDistributedTo(members) {
+		// current chain is still valid
+		return nil
+	}
+	chain := RenewSenderKey(old)
+	if chain == nil {
+		//panic("failed to generate sender key")
+		return nil
+	}
+	chain.Members = members
+	delegate.SaveSenderKey(chain, sender, group)
+	// build commands for the other members
+	messages := make([]InstantMessage, 0, len(members))
+	for _, item := range members {
+		if item.Equal(sender) {
+			continue
+		}
+		env := CreateEnvelope(sender, item, nil)
+		messages = append(messages, CreateInstantMessage(env, NewSenderKeyCommand(group, chain)))
+	}
+	return messages
+}
@@ -0,0 +1,223 @@
+/* license: https://mit-license.org
+ *
+ *  DIM-SDK : Decentralized Instant Messaging Software Development Kit
+ *
+ *                                Written in 2026 by Moky <albert.moky@gmail.com>
+ *
+ * ==============================================================================
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2026 Albert Moky
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ * ==============================================================================
+ */
+package sdk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	. "github.com/dimchat/sdk-go/core"
+)
+
+// PackageFraming defines how packages are separated in a stream
+type PackageFraming int
+
+const (
+	// LengthPrefixed: 4 bytes length (big endian) + package data
+	LengthPrefixed PackageFraming = iota
+
+	// NewlineDelimited: package data + '\n'
+	//
+	// NOTICE: only for JSON packages, compressed/binary data may contain '\n'
+	NewlineDelimited
+)
+
+// StreamProcessor processes a package and emits each response as soon as it's ready
+type StreamProcessor interface {
+
+	// ProcessPackageStream processes the package, and calls emit for each response package
+	//
+	// Returns: the error returned by emit
+	ProcessPackageStream(data []byte, emit func(pack []byte) error) error
+}
+
+// PackageStream reads packages from a stream, processes them with the messenger,
+// and writes each response back as soon as it's ready
+//
+// Usage:
+//
+//	stream := NewPackageStream(messenger, LengthPrefixed)
+//	err := stream.Serve(conn, conn)
+type PackageStream struct {
+	Processor Processor
+
+	Framing PackageFraming
+
+	// MaxPackageSize limits the bytes of one package
+	MaxPackageSize int
+
+	// ErrorHandler is called when processing a package panics (optional),
+	// the stream will continue with the next package
+	ErrorHandler func(pack []byte, err error)
+}
+
+func NewPackageStream(processor Processor, framing PackageFraming) *PackageStream {
+	return &PackageStream{
+		Processor:      processor,
+		Framing:        framing,
+		MaxPackageSize: DefaultPackageLimits.MaxPackageSize,
+	}
+}
+
+// Serve processes packages until the reader reaches EOF
+//
+// Parameters:
+//   - reader - Incoming packages
+//   - writer - Outgoing responses
+//
+// Returns: nil on EOF, or the read/write error
+func (stream *PackageStream) Serve(reader io.Reader, writer io.Writer) error {
+	input := bufio.NewReader(reader)
+	var pack []byte
+	var err error
+	for {
+		pack, err = stream.ReadPackage(input)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		} else if len(pack) == 0 {
+			// empty line
+			continue
+		}
+		if err = stream.processPackage(pack, writer); err != nil {
+			return err
+		}
+	}
+}
+
+// processPackage processes one package and writes the responses,
+// a panic in processing will be recovered so the stream can go on
+//
+// Returns: write error
+func (stream *PackageStream) processPackage(pack []byte, writer io.Writer) (err error) {
+	var writeErr error
+	emit := func(res []byte) error {
+		if len(res) == 0 {
+			return nil
+		}
+		writeErr = stream.WritePackage(writer, res)
+		return writeErr
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if handler := stream.ErrorHandler; handler != nil {
+				handler(pack, fmt.Errorf("package processing panic: %v", r))
+			}
+			// write error must stop the stream
+			err = writeErr
+		}
+	}()
+	if processor, ok := stream.Processor.(StreamProcessor); ok {
+		return processor.ProcessPackageStream(pack, emit)
+	}
+	for _, res := range stream.Processor.ProcessPackage(pack) {
+		if err = emit(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPackage reads one package from the stream
+//
+// Returns: package data, or io.EOF when the stream ends between packages
+func (stream *PackageStream) ReadPackage(reader *bufio.Reader) ([]byte, error) {
+	if stream.Framing == NewlineDelimited {
+		return stream.readLine(reader)
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(reader, head); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("package head truncated")
+		}
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(head)
+	if max := stream.MaxPackageSize; max > 0 && uint64(size) > uint64(max) {
+		return nil, fmt.Errorf("package size %d exceeds limit %d", size, max)
+	}
+	pack := make([]byte, size)
+	if _, err := io.ReadFull(reader, pack); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return pack, nil
+}
+
+func (stream *PackageStream) readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if max := stream.MaxPackageSize; max > 0 && len(line) > max+1 {
+			return nil, fmt.Errorf("package size exceeds limit %d", max)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		} else if err == io.EOF && len(line) > 0 {
+			// last line without '\n', trim '\r'
+			if n := len(line); line[n-1] == '\r' {
+				line = line[:n-1]
+			}
+			return line, nil
+		} else if err != nil {
+			return nil, err
+		}
+		// trim '\n' (and '\r')
+		line = line[:len(line)-1]
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+		return line, nil
+	}
+}
+
+// WritePackage writes one package to the stream
+func (stream *PackageStream) WritePackage(writer io.Writer, pack []byte) error {
+	var frame []byte
+	if stream.Framing == NewlineDelimited {
+		frame = make([]byte, 0, len(pack)+1)
+		frame = append(frame, pack...)
+		frame = append(frame, '\n')
+	} else {
+		frame = make([]byte, 4, len(pack)+4)
+		binary.BigEndian.PutUint32(frame, uint32(len(pack)))
+		frame = append(frame, pack...)
+	}
+	_, err := writer.Write(frame)
+	return err
+}